@@ -20,8 +20,10 @@ func main() {
 		fmt.Printf("in: %f, out: %x, %x\n", i, x, y)
 	}
 
-	s := can.Socket{}
-	s.BindToInterface(CAN_IFACE)
+	s, err := can.NewSocketBoundTo(CAN_IFACE)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	for _, l := range []string{
 		"123#01020304050607",
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
@@ -8,8 +10,18 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 
+	"github.com/team23asu/pican/pkg/avoid"
 	"github.com/team23asu/pican/pkg/can"
 	"github.com/team23asu/pican/pkg/demo"
+	"github.com/team23asu/pican/pkg/rnet"
+	"github.com/team23asu/pican/pkg/session"
+)
+
+var (
+	record   = flag.String("record", "", "directory to record the session to, see cmd/recorder -play")
+	strategy = flag.String("strategy", avoid.CLOSEST_PUSHBACK, "collision avoidance strategy to start with, key X switches between them")
+	governor = flag.Bool("governor", false, "start with the speed governor on, key G toggles it")
+	world    = flag.String("world", "", "floor plan to drive around, see pkg/floorplan and docs/floorplan-hallway.json. the built-in course if empty")
 )
 
 const (
@@ -24,9 +36,10 @@ type Demo struct {
 	chair     *demo.Chair
 	avoidance *demo.Avoider
 	world     *demo.World
+	recorder  *session.Recorder
 }
 
-func NewDemo() *Demo {
+func NewDemo() (*Demo, error) {
 	// create channels used for passing can frames from JSM to chair via our hardware.
 	jsmRead := make(chan *can.Frame, 1)
 	chairSend := make(chan *can.Frame, 1)
@@ -42,12 +55,18 @@ func NewDemo() *Demo {
 	// plug collision module in between the chair and JSM
 	a := demo.NewCollisionAvoider(jsmRead, chairSend)
 
+	// lay out the course around them
+	w, err := demo.NewWorld(c, a, *world)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Demo{
 		gamepads:  g,
 		chair:     c,
 		avoidance: a,
-		world:     demo.NewWorld(c, a),
-	}
+		world:     w,
+	}, nil
 }
 
 func (d *Demo) Draw(screen *ebiten.Image) {
@@ -61,6 +80,24 @@ func (d *Demo) Draw(screen *ebiten.Image) {
 	} else {
 		msg += "DISABLE AVOIDANCE: key Z\n"
 	}
+	msg += fmt.Sprintf("STRATEGY: key X (%s)\n", d.avoidance.Strategy().Name())
+	msg += "  " + d.avoidance.LastCommand().Explanation + "\n"
+	if d.avoidance.IsGoverned() {
+		msg += "SPEED GOVERNOR: key G (on)\n"
+		if l := d.avoidance.LastCommand().Limit; l != nil {
+			msg += "  " + l.String() + "\n"
+		}
+	} else {
+		msg += "SPEED GOVERNOR: key G (off)\n"
+	}
+	if d.world.ShowMargins() {
+		msg += "CLEARANCE: key C (shown)\n"
+	} else {
+		msg += "CLEARANCE: key C\n"
+	}
+	if d.world.TooClose() {
+		msg += "  TOO CLOSE\n"
+	}
 	msg += "RESET: right-most button\n"
 	msg += "SPEED: keys 1-5\n"
 	msg += "QUIT: select (center-left) or ESC\n"
@@ -70,29 +107,48 @@ func (d *Demo) Draw(screen *ebiten.Image) {
 func (d *Demo) Update() error {
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || d.gamepads.GetButton(ebiten.StandardGamepadButtonCenterLeft) {
 		log.Printf("key ESC pressed, goodbye!")
+		if d.recorder != nil {
+			if err := d.recorder.Close(); err != nil {
+				log.Printf("recording failed: %v", err)
+			}
+		}
 		os.Exit(0)
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
 		d.avoidance.SetDisabled(!d.avoidance.IsDisabled())
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyB) || d.gamepads.GetButton(ebiten.StandardGamepadButtonRightRight) {
-		d.chair.SetPosition(0, 0)
-		// g.chair.SetBearing(0.0)
-	}
-	if inpututil.IsKeyJustPressed(ebiten.Key1) {
-		d.chair.SetSpeed(0)
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		// cycle through the strategies, to compare them on the same course
+		names := avoid.Names()
+		next := names[0]
+		for i, name := range names {
+			if name == d.avoidance.Strategy().Name() {
+				next = names[(i+1)%len(names)]
+			}
+		}
+		s, err := avoid.New(next)
+		if err != nil {
+			return err
+		}
+		d.avoidance.SetStrategy(s)
 	}
-	if inpututil.IsKeyJustPressed(ebiten.Key2) {
-		d.chair.SetSpeed(1)
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		d.avoidance.SetGoverned(!d.avoidance.IsGoverned())
 	}
-	if inpututil.IsKeyJustPressed(ebiten.Key3) {
-		d.chair.SetSpeed(2)
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		d.world.SetShowMargins(!d.world.ShowMargins())
 	}
-	if inpututil.IsKeyJustPressed(ebiten.Key4) {
-		d.chair.SetSpeed(3)
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) || d.gamepads.GetButton(ebiten.StandardGamepadButtonRightRight) {
+		d.world.ResetChair()
 	}
-	if inpututil.IsKeyJustPressed(ebiten.Key5) {
-		d.chair.SetSpeed(4)
+	// speed keys act like the JSM's speed buttons, the chair follows the speed frame sent over the bus
+	for level, key := range []ebiten.Key{ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5} {
+		if inpututil.IsKeyJustPressed(key) {
+			err := d.gamepads.SendControl(rnet.Control{Kind: rnet.CONTROL_SPEED, JID: 1, Speed: rnet.SpeedPercent(level)})
+			if err != nil {
+				log.Printf("failed to send speed setting: %v", err)
+			}
+		}
 	}
 
 	err := d.gamepads.Update()
@@ -112,7 +168,25 @@ func (d *Demo) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func main() {
-	d := NewDemo()
+	flag.Parse()
+	d, err := NewDemo()
+	if err != nil {
+		log.Fatalf("failed to load the world: %v", err)
+	}
+	s, err := avoid.New(*strategy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d.avoidance.SetStrategy(s)
+	d.avoidance.SetGoverned(*governor)
+	if *record != "" {
+		r, err := session.NewRecorder(session.Config{Dir: *record})
+		if err != nil {
+			log.Fatalf("failed to start recording: %v", err)
+		}
+		d.recorder = r
+		d.world.SetRecorder(r)
+	}
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Team 23 Demo")
 	// ebiten.SetFullscreen(true)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/team23asu/pican/pkg/bussim"
+	"github.com/team23asu/pican/pkg/can"
+)
+
+var (
+	iface  = flag.String("iface", "vcan1", "name of CAN interface to send simulated chair traffic to (default: vcan1)")
+	script = flag.String("script", "", "optional fault script to play, see bussim.ParseScript")
+	seed   = flag.Int64("seed", 1, "random seed for jitter and payload corruption (default: 1)")
+)
+
+func main() {
+	flag.Parse()
+	socket, err := can.NewSocketBoundTo(*iface)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer socket.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	sim := bussim.New(socket, bussim.DefaultMessages(), *seed)
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			log.Fatalf("failed to open script: %v", err)
+		}
+		steps, err := bussim.ParseScript(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("failed to parse script %s: %v", *script, err)
+		}
+		go func() {
+			if err := sim.Play(ctx, steps); err != nil && ctx.Err() == nil {
+				log.Printf("script error: %v", err)
+			}
+		}()
+	}
+
+	log.Printf("simulating R-Net traffic on %s, Ctrl-C to stop", *iface)
+	sim.Run(ctx)
+}
@@ -1,70 +1,128 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/gateway"
 	"github.com/team23asu/pican/pkg/rnet"
+	"github.com/team23asu/pican/pkg/rules"
+	"github.com/team23asu/pican/pkg/session"
 )
 
 var (
-	joyIface = flag.String("joy", "vcan0", "name of CAN interface to read JSM events from (default: vcan0")
-	busIface = flag.String("bus", "vcan1", "name of CAN interface to write JSM events to (default: vcan1")
+	joyIface  = flag.String("joy", "vcan0", "name of CAN interface to read JSM events from (default: vcan0")
+	busIface  = flag.String("bus", "vcan1", "name of CAN interface to write JSM events to (default: vcan1")
+	failsafe  = flag.String("failsafe", "stop", "what to do with JSM frames if frame processing fails: passthrough or stop (default: stop)")
+	rulesFile = flag.String("rules", "", "JSON file of frame rules to apply, reloaded on SIGHUP (see docs/rules-beef.json)")
+	record    = flag.String("record", "", "directory to record frames on both sides and faults to, see cmd/recorder -play")
 )
 
 func main() {
 	flag.Parse()
+	mode, err := gateway.ParseFailSafeMode(*failsafe)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	jread, jsend, err := getchannels(*joyIface)
+	jsm, err := can.NewSocketBoundTo(*joyIface)
 	if err != nil {
 		log.Fatalf("failed to bind to %s: %v", *joyIface, err)
 	}
-	bread, bsend, err := getchannels(*busIface)
+	chair, err := can.NewSocketBoundTo(*busIface)
 	if err != nil {
 		log.Fatalf("failed to bind to %s: %v", *busIface, err)
 	}
 
-	for {
-		select {
-		case f := <-jread:
-			if rnet.IsMovementFrame(f.ID) {
-				// modify. for now, hard code it to BEEF
-				copy(f.Data[:], []uint8{0xBE, 0xEF})
+	cfg := gateway.Config{
+		JSM:      jsm,
+		Chair:    chair,
+		FailSafe: mode,
+		OnFault: func(f gateway.Fault) {
+			log.Printf("FAULT, switching to fail-safe mode %q: %s", mode, f)
+		},
+	}
+	var rec *session.Recorder
+	if *record != "" {
+		rec, err = session.NewRecorder(session.Config{Dir: *record})
+		if err != nil {
+			log.Fatalf("failed to start recording: %v", err)
+		}
+		// frames as they were read, frames as they were sent, and faults
+		cfg.JSM = rec.Tap(rnet.SIDE_JSM, *joyIface, jsm)
+		cfg.Chair = rec.Tap(rnet.SIDE_CHAIR, *busIface, chair)
+		cfg.Trace = func(t gateway.Trace) {
+			side := rnet.SIDE_CHAIR
+			if t.Dir == gateway.CHAIR_TO_JSM {
+				side = rnet.SIDE_JSM
 			}
-			// forward to bus
-			bsend <- f
-		case f := <-bread:
-			// forward to jsm
-			jsend <- f
+			rec.Record(session.FrameRecord(t.Egress, side, "gateway", &t.Frame))
+		}
+		onFault := cfg.OnFault
+		cfg.OnFault = func(f gateway.Fault) {
+			onFault(f)
+			rec.Record(session.Record{Time: f.Time, Kind: session.KIND_FAULT, Fault: fmt.Sprintf("%s %s: fail-safe %s: %s", f.Dir, f.Frame, mode, f.Reason)})
 		}
 	}
-}
+	gw := gateway.New(cfg)
 
-func getchannels(iface string) (read chan *can.Frame, send chan *can.Frame, err error) {
-	read = make(chan *can.Frame)
-	send = make(chan *can.Frame)
-	s, err := can.NewSocketBoundTo(iface)
-	if err != nil {
-		return nil, nil, err
+	// no rules file, no processors: the gateway just forwards frames
+	stopRules := func() {}
+	if *rulesFile != "" {
+		set, err := rules.Load(*rulesFile)
+		if err != nil {
+			log.Fatalf("failed to load rules: %v", err)
+		}
+		stopRules = set.Apply(gw)
+		log.Printf("loaded %d rules from %s", set.Len(), *rulesFile)
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		for {
-			f, err := s.Read()
-			if err != nil {
-				log.Printf("[%s] read error: %v", iface, err)
-			}
-			read <- f
-		}
+		<-sig
+		log.Printf("shutting down")
+		cancel()
 	}()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		for {
-			f := <-send
-			err := s.Send(f)
+		for range hup {
+			if *rulesFile == "" {
+				log.Printf("SIGHUP: no rules file to reload")
+				continue
+			}
+			// a broken file keeps the rules we have, rather than dropping them all
+			set, err := rules.Load(*rulesFile)
 			if err != nil {
-				log.Printf("[%s] send error: %v", iface, err)
+				log.Printf("SIGHUP: failed to reload rules, keeping the old ones: %v", err)
+				continue
 			}
+			stopRules()
+			stopRules = set.Apply(gw)
+			log.Printf("SIGHUP: reloaded %d rules from %s", set.Len(), *rulesFile)
 		}
 	}()
-	return read, send, nil
+
+	gw.Run(ctx)
+	if rec != nil {
+		if err := rec.Close(); err != nil {
+			log.Printf("recording failed: %v", err)
+		}
+	}
+	for _, dir := range []gateway.Direction{gateway.JSM_TO_CHAIR, gateway.CHAIR_TO_JSM} {
+		log.Printf("%s: %+v", dir, gw.Metrics(dir))
+		log.Printf("%s latency: %s", dir, gw.Latency(dir))
+		for id, l := range gw.LatencyByID(dir) {
+			log.Printf("%s latency of 0x%.8x: %s", dir, id, l)
+		}
+	}
 }
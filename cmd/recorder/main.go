@@ -0,0 +1,102 @@
+package main
+
+// records every frame on both sides of the gateway into a session (see pkg/session), or plays one back.
+// it only listens, so it can run next to cmd/jsmbuffer: socketcan loops frames sent by the gateway
+// back to other sockets on the same interface, so the session has both what came in and what went out.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+	"github.com/team23asu/pican/pkg/session"
+)
+
+var (
+	jsmIface    = flag.String("jsm", "vcan0", "name of CAN interface wired to the JSM (default: vcan0)")
+	busIface    = flag.String("bus", "vcan1", "name of CAN interface wired to the chair (default: vcan1)")
+	dir         = flag.String("dir", "", "directory to record the session to (default: session-<date>-<time>)")
+	segmentSize = flag.Int64("segment-size", session.DEFAULT_SEGMENT_SIZE, "bytes per segment file before starting a new one")
+	play        = flag.String("play", "", "instead of recording, print the records of the session in this directory")
+	from        = flag.Duration("from", 0, "with -play, start this far into the session")
+	speed       = flag.Float64("speed", 0, "with -play, 1 prints records at the pace they were recorded, 0 as fast as possible")
+)
+
+func main() {
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	if *play != "" {
+		if err := playback(ctx, *play); err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *dir == "" {
+		*dir = time.Now().Format("session-20060102-150405")
+	}
+	rec, err := session.NewRecorder(session.Config{Dir: *dir, SegmentSize: *segmentSize})
+	if err != nil {
+		log.Fatalf("failed to start recording: %v", err)
+	}
+	for _, side := range []struct {
+		side  rnet.Side
+		iface string
+	}{{rnet.SIDE_JSM, *jsmIface}, {rnet.SIDE_CHAIR, *busIface}} {
+		socket, err := can.NewSocketBoundTo(side.iface)
+		if err != nil {
+			log.Fatalf("failed to bind to %s: %v", side.iface, err)
+		}
+		go func(side rnet.Side, iface string, socket *can.Socket) {
+			for {
+				f, err := socket.Read()
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					log.Printf("[recorder] %s read error: %v", iface, err)
+					rec.Record(session.Record{Kind: session.KIND_FAULT, Fault: fmt.Sprintf("%s read error: %v", iface, err)})
+					time.Sleep(10 * time.Millisecond)
+					continue
+				}
+				rec.Frame(side, iface, f)
+			}
+		}(side.side, side.iface, socket)
+	}
+
+	log.Printf("recording %s and %s to %s, Ctrl-C to stop", *jsmIface, *busIface, *dir)
+	<-ctx.Done()
+	if err := rec.Close(); err != nil {
+		log.Fatalf("recording failed: %v", err)
+	}
+	ix := rec.Index()
+	log.Printf("recorded %s in %d segments", ix.End.Sub(ix.Start), len(ix.Segments))
+}
+
+// playback prints the records of a session, for scrubbing through it
+func playback(ctx context.Context, path string) error {
+	s, err := session.Open(path)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s: %s, from %s to %s", path, s.Duration(), s.Index.Start.Format(time.RFC3339), s.Index.End.Format(time.RFC3339))
+	return s.Replay(ctx, s.Index.Start.Add(*from), *speed, func(r session.Record) error {
+		fmt.Printf("%8.3f %s\n", r.Time.Sub(s.Index.Start).Seconds(), r)
+		return nil
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+// replays a recorded session through a collision avoidance strategy, or compares two runs.
+//
+//	replay -session session-bench -out after.run   # replay, save the run and diff it against the recording
+//	replay before.run after.run                     # diff two saved runs
+//
+// exits with status 1 if the runs differ.
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/team23asu/pican/pkg/avoid"
+	"github.com/team23asu/pican/pkg/replay"
+	"github.com/team23asu/pican/pkg/session"
+)
+
+var (
+	sessionDir = flag.String("session", "", "session directory to replay through the avoider")
+	strategy   = flag.String("strategy", avoid.CLOSEST_PUSHBACK, "with -session, avoidance strategy to replay through")
+	governor   = flag.Bool("governor", false, "with -session, limit the strategy's forward speed to what the chair can stop from")
+	out        = flag.String("out", "", "with -session, file to save the replayed run to")
+	against    = flag.String("against", "", "with -session, saved run to compare against instead of the recording")
+)
+
+func main() {
+	flag.Parse()
+
+	var a, b replay.Run
+	var err error
+	switch {
+	case *sessionDir != "" && flag.NArg() == 0:
+		a, b, err = replaySession()
+	case *sessionDir == "" && flag.NArg() == 2:
+		if a, err = replay.Load(flag.Arg(0)); err == nil {
+			b, err = replay.Load(flag.Arg(1))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "usage: %s -session <dir> [-out <run>] [-against <run>]\n       %s <run> <run>\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	diffs, err := replay.Diff(a, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	fmt.Printf("%d of %d frames differ\n", len(diffs), len(a))
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+}
+
+// replaySession returns the run to compare against and the replayed run
+func replaySession() (replay.Run, replay.Run, error) {
+	s, err := session.Open(*sessionDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	strat, err := avoid.New(*strategy)
+	if err != nil {
+		return nil, nil, err
+	}
+	if *governor {
+		strat = avoid.NewGovernor(strat)
+	}
+	replayed, err := replay.Replay(s, replay.Strategy(strat))
+	if err != nil {
+		return nil, nil, err
+	}
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		if err := replayed.Write(f); err != nil {
+			return nil, nil, err
+		}
+	}
+	if *against != "" {
+		want, err := replay.Load(*against)
+		return want, replayed, err
+	}
+	recorded, err := replay.Recorded(s)
+	return recorded, replayed, err
+}
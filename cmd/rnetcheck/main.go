@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+var (
+	before   = flag.String("before", "", "candump -L log captured without the gateway, or upstream of it (required)")
+	after    = flag.String("after", "", "candump -L log captured with the gateway in place (optional)")
+	jsmIface = flag.String("jsm", "vcan0", "interface in the logs on the JSM side of the gateway (default: vcan0)")
+	busIface = flag.String("bus", "vcan1", "interface in the logs on the chair side of the gateway (default: vcan1)")
+	verbose  = flag.Bool("v", false, "print every violation")
+)
+
+func main() {
+	flag.Parse()
+	if *before == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	b, err := load(*before)
+	if err != nil {
+		log.Fatalf("failed to load %s: %v", *before, err)
+	}
+	var a []rnet.Observation
+	if *after != "" {
+		a, err = load(*after)
+		if err != nil {
+			log.Fatalf("failed to load %s: %v", *after, err)
+		}
+	}
+
+	report := rnet.Compare(rnet.DefaultSpecs(), b, a)
+	if *verbose {
+		for _, v := range report.Before.Violations {
+			fmt.Printf("before: %s\n", v)
+		}
+		for _, v := range report.After.Violations {
+			fmt.Printf("after: %s\n", v)
+		}
+		fmt.Println()
+	}
+	if err := report.Write(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if len(report.After.Violations) > len(report.Before.Violations) {
+		os.Exit(1)
+	}
+}
+
+// load reads a candump log, mapping its interfaces to sides of the gateway
+func load(path string) ([]rnet.Observation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := can.ReadLog(f)
+	if err != nil {
+		return nil, err
+	}
+	obs := make([]rnet.Observation, 0, len(entries))
+	for _, e := range entries {
+		side := rnet.SIDE_ANY
+		switch e.Iface {
+		case *jsmIface:
+			side = rnet.SIDE_JSM
+		case *busIface:
+			side = rnet.SIDE_CHAIR
+		}
+		obs = append(obs, rnet.Observation{Time: e.Time, Side: side, Frame: e.Frame})
+	}
+	return obs, nil
+}
@@ -0,0 +1,75 @@
+package avoid
+
+// what we know about the chair's size and how it moves. the demo simulates the chair with the same numbers.
+
+import "math"
+
+const (
+	MILES_PER_HOUR = 0.44704 // 1 mph, in meters per second
+
+	// page 37, Permobil M300 Corpus HD User Manual:
+	// https://permobilwebcdn.azureedge.net/media/yeua1ubg/m300_corpus_hd-user_manual-eng-us-334794.pdf
+	CHAIR_WIDTH_METERS           = 0.860
+	CHAIR_LENGTH_METERS          = 1.190
+	CHAIR_MIN_TURN_RADIUS_METERS = 0.560
+	CHAIR_WEIGHT_POUNDS          = 450.0
+
+	// not in the manual, guesses until we measure them on the chair
+	CHAIR_MAX_ACCEL_M_S2           = 0.5 // speeding up
+	CHAIR_MAX_DECEL_M_S2           = 1.0 // braking
+	CHAIR_MAX_ANGULAR_ACCEL_RAD_S2 = 1.5
+	CHAIR_REVERSE_SPEED_FRACTION   = 0.5 // top speed backwards, relative to forwards
+)
+
+var (
+	// top speed at each of the JSM's 5 speed settings
+	CHAIR_INDOOR_SPEEDS_M_S = []float64{
+		0.8 * MILES_PER_HOUR, // 0.3576 m/s
+		1.3 * MILES_PER_HOUR,
+		1.7 * MILES_PER_HOUR,
+		2.2 * MILES_PER_HOUR,
+		2.7 * MILES_PER_HOUR,
+	}
+
+	CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S = []float64{
+		0.319,
+		0.429,
+		0.565,
+		0.693,
+		0.782,
+	}
+)
+
+// speedLevel clamps a speed setting to the known ones
+func speedLevel(level int) int {
+	if level < 0 {
+		return 0
+	}
+	if level >= len(CHAIR_INDOOR_SPEEDS_M_S) {
+		return len(CHAIR_INDOOR_SPEEDS_M_S) - 1
+	}
+	return level
+}
+
+// MaxTurnRate is the fastest the chair turns, in rad/s, driving at v m/s: no tighter than its minimum turn radius,
+// and no faster than the speed setting allows
+func MaxTurnRate(v float64, level int) float64 {
+	return math.Min(math.Abs(v)/CHAIR_MIN_TURN_RADIUS_METERS, CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S[speedLevel(level)])
+}
+
+// TurnRate is how fast the chair turns with the joystick's side axis at side, positive to the right, driving at v.
+// counterclockwise is positive, so pushing right driving forwards is negative. like a car, it turns the other way
+// backing up, and not at all standing still.
+func TurnRate(side, v float64, level int) float64 {
+	return -side * math.Copysign(MaxTurnRate(v, level), v)
+}
+
+// TurnSide is the side axis that makes the chair turn at w driving at v, the opposite of TurnRate.
+// it's 0 standing still, and past ±1 for turns tighter than the chair can make.
+func TurnSide(w, v float64, level int) float64 {
+	wmax := MaxTurnRate(v, level)
+	if wmax == 0 {
+		return 0
+	}
+	return -w / math.Copysign(wmax, v)
+}
@@ -0,0 +1,184 @@
+package avoid
+
+// dynamic window approach, after Fox, Burgard & Thrun, "The Dynamic Window Approach to Collision Avoidance" (1997).
+// the chair drives like a car that can't turn tighter than CHAIR_MIN_TURN_RADIUS_METERS, it can't move sideways,
+// so rather than adding sideways pushback to the joystick this works with (forward speed, turn rate) pairs, turned
+// into each other with TurnRate and TurnSide, the same way the demo drives its chair.
+// it samples the pairs the chair can reach before the next decision given its acceleration limits, drives each one
+// forward for a couple of seconds against the obstacles the sensors see, throws out the ones that couldn't stop before
+// hitting something, and picks the one closest to what the user asks for. the pair is turned back into a joystick
+// position, which Apply encodes as an R-Net movement frame.
+
+import (
+	"fmt"
+	"math"
+)
+
+const DYNAMIC_WINDOW = "dwa"
+
+type DWA struct {
+	MaxAccel        float64 // m/s^2
+	MaxDecel        float64 // m/s^2
+	MaxAngularAccel float64 // rad/s^2
+	ReverseFraction float64 // top speed backwards, relative to forwards
+	Period          float64 // seconds between decisions, how far the window reaches
+	Horizon         float64 // seconds to simulate each trajectory for
+	Step            float64 // seconds per simulation step
+	Samples         int     // per axis of the window
+	Width           float64 // meters, the chair's footprint that must stay clear of obstacles, centered on the chair
+	Length          float64 // meters
+	ClearanceWeight float64 // how much a trajectory with more room around it is preferred, 0 only looks at the intent
+}
+
+func NewDWA() *DWA {
+	return &DWA{
+		MaxAccel:        CHAIR_MAX_ACCEL_M_S2,
+		MaxDecel:        CHAIR_MAX_DECEL_M_S2,
+		MaxAngularAccel: CHAIR_MAX_ANGULAR_ACCEL_RAD_S2,
+		ReverseFraction: CHAIR_REVERSE_SPEED_FRACTION,
+		Period:          0.1,
+		Horizon:         2.0,
+		Step:            0.1,
+		Samples:         11,
+		Width:           CHAIR_WIDTH_METERS,
+		Length:          CHAIR_LENGTH_METERS,
+		ClearanceWeight: 0.1,
+	}
+}
+
+func (d *DWA) Name() string {
+	return DYNAMIC_WINDOW
+}
+
+// obstacles returns the points the sensors see, in the chair's frame: x to the right, y forward, from its center
+func obstacles(readings []Reading) []Vector2D {
+	var obs []Vector2D
+	for _, r := range readings {
+		if r.Triggered() {
+			obs = append(obs, r.Obstacle())
+		}
+	}
+	return obs
+}
+
+// gap returns how far o is from the chair's footprint, with the chair's center at x, y facing heading. 0 inside it
+func (d *DWA) gap(o Vector2D, x, y, heading float64) float64 {
+	dx, dy := o.X-x, o.Y-y
+	ahead := dx*math.Cos(heading) + dy*math.Sin(heading)
+	right := dx*math.Sin(heading) - dy*math.Cos(heading)
+	return math.Hypot(math.Max(math.Abs(right)-d.Width/2, 0), math.Max(math.Abs(ahead)-d.Length/2, 0))
+}
+
+// clearance drives at (v, w) from the chair's current position for the horizon and returns how close its footprint
+// gets to an obstacle, and how far it gets before it hits one. both are at most limit.
+func (d *DWA) clearance(v, w float64, obs []Vector2D, limit float64) (clear, free float64) {
+	clear, free = limit, limit
+	x, y, heading := 0.0, 0.0, math.Pi/2
+	for t := 0.0; t <= d.Horizon; t += d.Step {
+		for _, o := range obs {
+			clear = math.Min(clear, d.gap(o, x, y, heading))
+		}
+		if clear <= 0 {
+			return 0, math.Abs(v) * t
+		}
+		x += v * math.Cos(heading) * d.Step
+		y += v * math.Sin(heading) * d.Step
+		heading += w * d.Step
+	}
+	return clear, free
+}
+
+func (d *DWA) Decide(in Input) Command {
+	obs := obstacles(in.Readings)
+	if len(obs) == 0 {
+		return Command{Vector: in.Intent, Explanation: "no sensor triggered"}
+	}
+	level := speedLevel(in.Chair.Speed)
+	vmax := CHAIR_INDOOR_SPEEDS_M_S[level]
+	wmax := CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S[level]
+
+	// what the user asks for, as a speed and a turn rate. side is positive to the right, turning right is negative
+	vWant := in.Intent.Y * vmax
+	wWant := TurnRate(in.Intent.X, vWant, level)
+
+	// the dynamic window: what the chair can get to by the next decision
+	cur := in.Chair
+	vLow := math.Max(cur.Velocity-d.MaxDecel*d.Period, -vmax*d.ReverseFraction)
+	vHigh := math.Min(cur.Velocity+d.MaxAccel*d.Period, vmax)
+	if cur.Velocity < 0 {
+		// braking while reversing is speeding up towards zero
+		vHigh = math.Min(cur.Velocity+d.MaxDecel*d.Period, vmax)
+		vLow = math.Max(cur.Velocity-d.MaxAccel*d.Period, -vmax*d.ReverseFraction)
+	}
+	wLow, wHigh := cur.TurnRate-d.MaxAngularAccel*d.Period, cur.TurnRate+d.MaxAngularAccel*d.Period
+	if vLow > vHigh {
+		// going faster than this speed setting allows, slow down as fast as possible
+		vHigh = vLow
+	}
+
+	// no turn is tighter than the chair can make at each speed. a chair turning faster than that, say after slowing
+	// down, gets there as quickly as it can rather than having nowhere to go
+	turns := func(v float64) (low, high float64) {
+		wm := MaxTurnRate(v, level)
+		return clamp(wLow, -wm, wm), clamp(wHigh, -wm, wm)
+	}
+
+	// a grid over the window, plus the intent itself if the chair can get there
+	type pair struct{ v, w float64 }
+	var pairs []pair
+	for i := 0; i < d.Samples; i++ {
+		v := sample(vLow, vHigh, i, d.Samples)
+		low, high := turns(v)
+		for j := 0; j < d.Samples; j++ {
+			pairs = append(pairs, pair{v, sample(low, high, j, d.Samples)})
+		}
+	}
+	v := clamp(vWant, vLow, vHigh)
+	low, high := turns(v)
+	pairs = append(pairs, pair{v, clamp(wWant, low, high)})
+
+	limit := d.Horizon * vmax
+	found, bestCost := false, math.Inf(1)
+	var bestV, bestW, bestFree float64
+	for _, p := range pairs {
+		clear, free := d.clearance(p.v, p.w, obs, limit)
+		// it must be able to stop before it hits anything, after driving on until the next decision.
+		// going backwards is always fine, we can't see behind
+		if p.v > 0 && p.v*d.Period+p.v*p.v/(2*d.MaxDecel) > free {
+			continue
+		}
+		// closest to the intent, with some preference for room around the chair and ahead of it
+		cost := math.Abs(p.v-vWant)/vmax + math.Abs(p.w-wWant)/wmax - d.ClearanceWeight*(clear+free)/limit
+		if cost < bestCost {
+			found, bestCost = true, cost
+			bestV, bestW, bestFree = p.v, p.w, free
+		}
+	}
+	if !found {
+		return Command{Vector: Vector2D{}, Pushback: in.Intent.Mul(-1), Intervened: true, Explanation: "no safe trajectory, stopping"}
+	}
+
+	out := Vector2D{X: TurnSide(bestW, bestV, level), Y: bestV / vmax}
+	if out.Sub(in.Intent).Mag() < 0.01 {
+		return Command{Vector: in.Intent, Explanation: fmt.Sprintf("on course, %.2fm free", bestFree)}
+	}
+	return Command{
+		Vector:      out,
+		Pushback:    out.Sub(in.Intent),
+		Intervened:  true,
+		Explanation: fmt.Sprintf("%.2fm/s turning %.2frad/s, %.2fm free", bestV, bestW, bestFree),
+	}
+}
+
+// clamp returns x limited to low through high
+func clamp(x, low, high float64) float64 {
+	return math.Max(low, math.Min(high, x))
+}
+
+// sample returns the i-th of n evenly spaced values from low to high
+func sample(low, high float64, i, n int) float64 {
+	if n <= 1 {
+		return (low + high) / 2
+	}
+	return low + (high-low)*float64(i)/float64(n-1)
+}
@@ -0,0 +1,136 @@
+package avoid
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+func TestDWA(t *testing.T) {
+	// meters, with the sensors seeing up to 2m
+	at := func(left, center, right float64) []Reading {
+		return []Reading{
+			{Name: "front-left", AimDeg: 135, Meters: left, Threshold: 2},
+			{Name: "front-center", AimDeg: 90, Meters: center, Threshold: 2},
+			{Name: "front-right", AimDeg: 45, Meters: right, Threshold: 2},
+		}
+	}
+	// the same, with the center sensor on the front of the chair rather than in its middle
+	mounted := func(left, center, right float64) []Reading {
+		r := at(left, center, right)
+		r[1].Mount = Vector2D{Y: CHAIR_LENGTH_METERS / 2}
+		return r
+	}
+	vmax := CHAIR_INDOOR_SPEEDS_M_S[2]
+	cruising := State{Speed: 2, Velocity: vmax}
+
+	type test struct {
+		name       string
+		intent     Vector2D
+		readings   []Reading
+		chair      State
+		intervened bool
+		slower     bool   // commanded forward speed below the intent's
+		explain    string // start of the explanation
+	}
+	tests := []test{
+		{name: "clear", intent: Vector2D{Y: 1}, readings: at(2, 2, 2), chair: cruising, explain: "no sensor triggered"},
+		{name: "far", intent: Vector2D{Y: 1}, readings: at(2, 1.9, 2), chair: cruising, explain: "on course"},
+		{name: "wall ahead", intent: Vector2D{Y: 1}, readings: at(2, 0.7, 2), chair: cruising, intervened: true, slower: true},
+		// 0.7m from the front of the chair is far enough to stop in
+		{name: "wall ahead of the front", intent: Vector2D{Y: 1}, readings: mounted(2, 0.7, 2), chair: cruising, explain: "on course"},
+		{name: "boxed in", intent: Vector2D{Y: 1}, readings: at(0.4, 0.4, 0.4), chair: cruising, intervened: true, slower: true, explain: "no safe trajectory"},
+		// nothing behind the chair is seen, backing up is left alone
+		{name: "reverse", intent: Vector2D{Y: -0.5}, readings: at(0.6, 0.6, 0.6), chair: State{Speed: 2, Velocity: -vmax / 2}, explain: "on course"},
+	}
+	d := NewDWA()
+	for _, test := range tests {
+		got := d.Decide(Input{Intent: test.intent, Readings: test.readings, Chair: test.chair})
+		if got.Intervened != test.intervened || (got.Vector.Y < test.intent.Y-0.01) != test.slower || !strings.HasPrefix(got.Explanation, test.explain) {
+			t.Fatalf("%s: Decide(%v), expected: intervened %t, slower %t, %q, got: %+v",
+				test.name, test.intent, test.intervened, test.slower, test.explain, got)
+		}
+	}
+}
+
+func TestDWAWindow(t *testing.T) {
+	// whatever it picks, the chair must be able to do it: within its acceleration limits of what it's doing now,
+	// and no tighter than its turn radius
+	d := NewDWA()
+	readings := []Reading{
+		{Name: "front-left", AimDeg: 135, Meters: 1.2, Threshold: 2},
+		{Name: "front-center", AimDeg: 90, Meters: 1.0, Threshold: 2},
+		{Name: "front-right", AimDeg: 45, Meters: 2, Threshold: 2},
+	}
+	type test struct {
+		intent     Vector2D
+		chair      State
+		intervened bool
+	}
+	tests := []test{
+		{intent: Vector2D{Y: 1}, chair: State{Speed: 2, Velocity: 0.5}, intervened: true},
+		{intent: Vector2D{X: -1, Y: 1}, chair: State{Speed: 4, Velocity: 1.0, TurnRate: 0.2}, intervened: true},
+		{intent: Vector2D{Y: 1}, chair: State{Speed: 0}, intervened: true},
+		{intent: Vector2D{X: 0.5, Y: 0.5}, chair: State{Speed: 1, Velocity: 0.3, TurnRate: -0.1}},
+		{intent: Vector2D{Y: 0.2}, chair: State{Speed: 1, Velocity: 0.1}},
+		{intent: Vector2D{X: 1, Y: 0.2}, chair: State{Speed: 1, Velocity: 0.1, TurnRate: -0.1}},
+	}
+	for _, test := range tests {
+		got := d.Decide(Input{Intent: test.intent, Readings: readings, Chair: test.chair})
+		if got.Intervened != test.intervened {
+			t.Fatalf("Decide(%v, %+v), expected: intervened %t, got: %+v", test.intent, test.chair, test.intervened, got)
+		}
+		level := speedLevel(test.chair.Speed)
+		v := got.Vector.Y * CHAIR_INDOOR_SPEEDS_M_S[level]
+		w := TurnRate(got.Vector.X, v, level)
+		stopping := strings.HasPrefix(got.Explanation, "no safe trajectory")
+		if !stopping && (v < test.chair.Velocity-d.MaxDecel*d.Period-1e-9 || v > test.chair.Velocity+d.MaxAccel*d.Period+1e-9) {
+			t.Fatalf("Decide(%v, %+v), expected: speed within the window, got: %.3fm/s (%+v)", test.intent, test.chair, v, got)
+		}
+		if !stopping && (w < test.chair.TurnRate-d.MaxAngularAccel*d.Period-1e-9 || w > test.chair.TurnRate+d.MaxAngularAccel*d.Period+1e-9) {
+			t.Fatalf("Decide(%v, %+v), expected: turn within the window, got: %.3frad/s (%+v)", test.intent, test.chair, w, got)
+		}
+		if math.Abs(got.Vector.X) > 1+1e-9 || math.Abs(w) > math.Abs(v)/CHAIR_MIN_TURN_RADIUS_METERS+1e-9 {
+			t.Fatalf("Decide(%v, %+v), expected: turn within the chair's radius, got: %.3fm/s at %.3frad/s (%+v)", test.intent, test.chair, v, w, got)
+		}
+	}
+}
+
+func TestDWATurningTooFast(t *testing.T) {
+	// a chair turning faster than it can at the speed it's going at, e.g. one that just slowed down at full lock,
+	// still has somewhere to go. it used to find every turn in its window too tight and stop.
+	readings := []Reading{
+		{Name: "front-left", AimDeg: 135, Meters: 1.0, Threshold: 2},
+		{Name: "front-center", AimDeg: 90, Meters: 0.95, Threshold: 2},
+		{Name: "front-right", AimDeg: 45, Meters: 1.0, Threshold: 2},
+	}
+	d := NewDWA()
+	for level := range CHAIR_INDOOR_SPEEDS_M_S {
+		for _, side := range []float64{-1, 1} {
+			v := CHAIR_INDOOR_SPEEDS_M_S[level] / 4
+			chair := State{Speed: level, Velocity: v, TurnRate: -side * 2 * CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S[level]}
+			intent := Vector2D{X: side, Y: 0.25}
+			got := d.Decide(Input{Intent: intent, Readings: readings, Chair: chair})
+			if strings.HasPrefix(got.Explanation, "no safe trajectory") || got.Vector.Y <= 0 {
+				t.Fatalf("Decide(%v, %+v), expected: keep turning, got: %+v", intent, chair, got)
+			}
+			if w := TurnRate(got.Vector.X, got.Vector.Y*CHAIR_INDOOR_SPEEDS_M_S[level], level); math.Abs(w) > math.Abs(chair.TurnRate) {
+				t.Fatalf("Decide(%v, %+v), expected: a turn no faster than now, got: %.3frad/s (%+v)", intent, chair, w, got)
+			}
+		}
+	}
+}
+
+func TestDWAApply(t *testing.T) {
+	// stopping for a wall right ahead comes out as a centered joystick
+	f, _ := can.FromLog("02000100#0064")
+	got, cmd := Apply(NewDWA(), Input{
+		Readings: []Reading{{Name: "front-center", AimDeg: 90, Meters: 0.3, Threshold: 2}},
+		Chair:    State{Speed: 2},
+	}, f)
+	if !cmd.Intervened || got.String() != "02000100#0000" {
+		t.Fatalf("Apply(%v), expected: 02000100#0000, got: %v (%+v)", f, got, cmd)
+	}
+}
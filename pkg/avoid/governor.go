@@ -0,0 +1,160 @@
+package avoid
+
+// the governor caps the forward speed of whatever another strategy decides, so the chair can always stop before it
+// reaches what the sensors see. a fixed trigger distance is too close at top speed and too far when creeping
+// through a doorway, so the limit follows the stopping distance: the distance covered before the chair reacts plus
+// v²/2a while braking, plus a margin, must fit within the measured range. the range rate tells how fast an obstacle
+// is closing in, which gives the time to collision and, for obstacles moving towards the chair, how much of the
+// range will be gone by the time the chair has stopped.
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// SpeedLimit is the forward speed cap the governor is enforcing, and the sensor it comes from
+type SpeedLimit struct {
+	Sensor    string
+	Meters    float64 // range
+	RangeRate float64 // m/s, negative when closing in
+	TTC       float64 // seconds to collision at the current closing speed, +Inf if not closing in
+	MaxSpeed  float64 // fraction of the current speed setting's top speed, 0 through 1
+}
+
+func (l SpeedLimit) String() string {
+	ttc := "not closing in"
+	if !math.IsInf(l.TTC, 1) {
+		ttc = fmt.Sprintf("%.1fs to collision", l.TTC)
+	}
+	return fmt.Sprintf("speed limit %.0f%%, %s at %.2fm, %s", 100*l.MaxSpeed, l.Sensor, l.Meters, ttc)
+}
+
+// Governor wraps another strategy and limits its forward speed
+type Governor struct {
+	Decel    float64 // m/s^2 the chair can be counted on to brake at
+	Reaction float64 // seconds before braking starts: until the next decision, plus the chair's own delay
+	Margin   float64 // meters to keep between the chair and an obstacle once stopped
+
+	mu       sync.Mutex
+	strategy Strategy               // can be swapped while another goroutine decides, see SetStrategy
+	seen     map[string]rangeSample // last triggered reading of each sensor, for the range rate
+}
+
+// rangeSample is a reading at a point in time
+type rangeSample struct {
+	at     time.Time
+	meters float64
+	rate   float64
+}
+
+func NewGovernor(s Strategy) *Governor {
+	return &Governor{
+		Decel:    CHAIR_MAX_DECEL_M_S2,
+		Reaction: 0.2,
+		Margin:   0.1,
+		strategy: s,
+		seen:     map[string]rangeSample{},
+	}
+}
+
+// SetStrategy changes the strategy whose speed is limited, decisions already being made finish with the old one
+func (g *Governor) SetStrategy(s Strategy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.strategy = s
+}
+
+func (g *Governor) Strategy() Strategy {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.strategy
+}
+
+func (g *Governor) Name() string {
+	return g.Strategy().Name()
+}
+
+// rangeRate updates the history of a sensor and returns how fast its range changes. without timestamps,
+// obstacles are assumed to stand still, so only the chair's own movement towards them counts.
+func (g *Governor) rangeRate(r Reading, in Input) float64 {
+	static := -in.Chair.Velocity * math.Sin(r.AimDeg*math.Pi/180)
+	if !r.Triggered() || in.Time.IsZero() {
+		delete(g.seen, r.Name)
+		return static
+	}
+	prev, ok := g.seen[r.Name]
+	if !ok {
+		g.seen[r.Name] = rangeSample{at: in.Time, meters: r.Meters, rate: static}
+		return static
+	}
+	dt := in.Time.Sub(prev.at).Seconds()
+	if dt <= 0 {
+		// same measurement as last time
+		return prev.rate
+	}
+	rate := (r.Meters - prev.meters) / dt
+	g.seen[r.Name] = rangeSample{at: in.Time, meters: r.Meters, rate: rate}
+	return rate
+}
+
+// maxSpeed returns the fastest the chair can go in m/s and still stop within d meters
+func (g *Governor) maxSpeed(d float64) float64 {
+	if d <= 0 {
+		return 0
+	}
+	// v*Reaction + v²/2a = d
+	a, t := g.Decel, g.Reaction
+	return -a*t + math.Sqrt(a*a*t*t+2*a*d)
+}
+
+// Limit returns the tightest speed limit the readings impose, nil if there is none
+func (g *Governor) Limit(in Input) *SpeedLimit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seen == nil {
+		g.seen = map[string]rangeSample{}
+	}
+	vmax := CHAIR_INDOOR_SPEEDS_M_S[speedLevel(in.Chair.Speed)]
+	stopping := g.Reaction + math.Max(in.Chair.Velocity, 0)/g.Decel
+	var limit *SpeedLimit
+	for _, r := range in.Readings {
+		rate := g.rangeRate(r, in)
+		if !r.Triggered() {
+			continue
+		}
+		ttc := math.Inf(1)
+		if rate < 0 {
+			ttc = r.Meters / -rate
+		}
+		// whatever closes in faster than the chair drives towards it is moving itself, and keeps moving while we stop
+		own := math.Max(in.Chair.Velocity*math.Sin(r.AimDeg*math.Pi/180), 0)
+		moving := math.Max(-rate-own, 0)
+		room := r.Meters - g.Margin - moving*stopping
+		max := math.Min(g.maxSpeed(room)/vmax, 1)
+		if max < 1 && (limit == nil || max < limit.MaxSpeed) {
+			limit = &SpeedLimit{Sensor: r.Name, Meters: r.Meters, RangeRate: rate, TTC: ttc, MaxSpeed: max}
+		}
+	}
+	return limit
+}
+
+func (g *Governor) Decide(in Input) Command {
+	cmd := g.Strategy().Decide(in)
+	limit := g.Limit(in)
+	if limit == nil {
+		return cmd
+	}
+	cmd.Limit = limit
+	if cmd.Vector.Y <= limit.MaxSpeed {
+		return cmd
+	}
+	// the side axis is left alone, so the user can still turn away from the obstacle
+	capped := Vector2D{X: cmd.Vector.X, Y: limit.MaxSpeed}
+	cmd.Pushback = cmd.Pushback.Add(capped.Sub(cmd.Vector))
+	cmd.Vector = capped
+	cmd.Intervened = true
+	cmd.Explanation = fmt.Sprintf("%s; %s", cmd.Explanation, limit)
+	return cmd
+}
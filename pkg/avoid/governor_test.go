@@ -0,0 +1,115 @@
+package avoid
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// passThrough never intervenes, so only the governor acts
+type passThrough struct{}
+
+func (passThrough) Name() string { return "pass-through" }
+
+func (passThrough) Decide(in Input) Command {
+	return Command{Vector: in.Intent, Explanation: "untouched"}
+}
+
+func TestGovernor(t *testing.T) {
+	ahead := func(meters float64) []Reading {
+		return []Reading{{Name: "front-center", AimDeg: 90, Meters: meters, Threshold: 2}}
+	}
+	type test struct {
+		name       string
+		intent     Vector2D
+		readings   []Reading
+		speed      int
+		limit      float64 // MaxSpeed, 0 for no limit
+		want       Vector2D
+		intervened bool
+	}
+	tests := []test{
+		{name: "clear", intent: Vector2D{Y: 1}, readings: ahead(2), speed: 4, want: Vector2D{Y: 1}},
+		// stops within 1.16m from 0.76m/s, plenty of room
+		{name: "slow setting", intent: Vector2D{Y: 1}, readings: ahead(1), speed: 2, want: Vector2D{Y: 1}},
+		// but not from 1.21m/s
+		{name: "fast setting", intent: Vector2D{Y: 1}, readings: ahead(1), speed: 4, limit: 0.958, want: Vector2D{Y: 0.958}, intervened: true},
+		// 0.2m left to stop in, the side axis stays as it was
+		{name: "close", intent: Vector2D{X: 0.5, Y: 1}, readings: ahead(0.3), speed: 2, limit: 0.610, want: Vector2D{X: 0.5, Y: 0.610}, intervened: true},
+		// already slower than the limit, which is still reported
+		{name: "under the limit", intent: Vector2D{Y: 0.3}, readings: ahead(0.3), speed: 2, limit: 0.610, want: Vector2D{Y: 0.3}},
+		{name: "touching", intent: Vector2D{Y: 1}, readings: ahead(0.05), speed: 2, limit: 0.001, want: Vector2D{}, intervened: true},
+		{name: "reverse", intent: Vector2D{Y: -1}, readings: ahead(0.05), speed: 2, limit: 0.001, want: Vector2D{Y: -1}},
+	}
+	for _, test := range tests {
+		g := NewGovernor(passThrough{})
+		got := g.Decide(Input{Intent: test.intent, Readings: test.readings, Chair: State{Speed: test.speed}})
+		limit := 0.0
+		if got.Limit != nil {
+			limit = math.Max(got.Limit.MaxSpeed, 0.001)
+		}
+		if got.Vector.Sub(test.want).Mag() > 0.001 || got.Intervened != test.intervened || math.Abs(limit-test.limit) > 0.001 {
+			t.Fatalf("%s: Decide(%v), expected: %v (intervened %t, limit %.3f), got: %+v (limit %+v)",
+				test.name, test.intent, test.want, test.intervened, test.limit, got, got.Limit)
+		}
+	}
+}
+
+func TestGovernorRangeRate(t *testing.T) {
+	// something coming at a standing chair at 1m/s: 1s to collision, and 0.2m of the range are gone
+	// before the chair reacts
+	g := NewGovernor(passThrough{})
+	start := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	type test struct {
+		offset time.Duration
+		meters float64
+		rate   float64
+		ttc    float64
+		limit  float64
+	}
+	tests := []test{
+		// no history yet, assumed to stand still
+		{offset: 0, meters: 1.5, rate: 0, ttc: math.Inf(1), limit: 1},
+		{offset: 500 * time.Millisecond, meters: 1.0, rate: -1, ttc: 1, limit: 0.828},
+		// the same measurement again doesn't change the rate
+		{offset: 500 * time.Millisecond, meters: 1.0, rate: -1, ttc: 1, limit: 0.828},
+		// it stopped
+		{offset: time.Second, meters: 1.0, rate: 0, ttc: math.Inf(1), limit: 0.958},
+	}
+	for _, test := range tests {
+		got := g.Limit(Input{
+			Readings: []Reading{{Name: "front-center", AimDeg: 90, Meters: test.meters, Threshold: 2}},
+			Chair:    State{Speed: 4},
+			Time:     start.Add(test.offset),
+		})
+		if got == nil {
+			got = &SpeedLimit{RangeRate: 0, TTC: math.Inf(1), MaxSpeed: 1}
+		}
+		if math.Abs(got.RangeRate-test.rate) > 0.001 || got.TTC != test.ttc && math.Abs(got.TTC-test.ttc) > 0.001 || math.Abs(got.MaxSpeed-test.limit) > 0.001 {
+			t.Fatalf("Limit(%v, %.2fm), expected: %.2fm/s, %.2fs, %.3f, got: %+v", test.offset, test.meters, test.rate, test.ttc, test.limit, got)
+		}
+	}
+}
+
+// the demo swaps strategies from its game loop while frames are being decided, run with -race
+func TestGovernorSetStrategy(t *testing.T) {
+	g := NewGovernor(passThrough{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			g.Decide(Input{Intent: Vector2D{Y: 1}, Chair: State{Speed: 2}})
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if i%2 == 0 {
+			g.SetStrategy(NewClosestPushback())
+		} else {
+			g.SetStrategy(passThrough{})
+		}
+	}
+	<-done
+	if got := g.Name(); got != "pass-through" {
+		t.Fatalf("Name(), expected: pass-through, got: %s", got)
+	}
+}
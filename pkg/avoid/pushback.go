@@ -0,0 +1,70 @@
+package avoid
+
+import (
+	"fmt"
+	"math"
+)
+
+const CLOSEST_PUSHBACK = "closest-pushback"
+
+// Push is how a triggered sensor pushes back on the joystick
+type Push struct {
+	AngleDeg float64 // direction of the push in the chair's frame, like the joystick: 0 is right, 90 forward
+	Gain     float64 // length of the push with the obstacle right at the sensor
+}
+
+// ClosestPushback is the original demo avoidance: take the closest triggered sensor, add its pushback
+// to the intent, scaled by how close the obstacle is, then scale back to the length of the intent to avoid jerk.
+type ClosestPushback struct {
+	Pushes  map[string]Push // by sensor name
+	Default Push            // for sensors not in Pushes
+}
+
+func NewClosestPushback() *ClosestPushback {
+	return &ClosestPushback{
+		Pushes: map[string]Push{
+			"front-center": {AngleDeg: -90, Gain: 0.5}, // back
+			"front-left":   {AngleDeg: 0, Gain: 2.0},   // right
+			"front-right":  {AngleDeg: 180, Gain: 2.0}, // left
+		},
+		Default: Push{AngleDeg: -90, Gain: 0.5},
+	}
+}
+
+func (p *ClosestPushback) Name() string {
+	return CLOSEST_PUSHBACK
+}
+
+func (p *ClosestPushback) Decide(in Input) Command {
+	var closest *Reading
+	for i, r := range in.Readings {
+		if r.Triggered() && (closest == nil || closest.Meters > r.Meters) {
+			closest = &in.Readings[i]
+		}
+	}
+	if closest == nil {
+		return Command{Vector: in.Intent, Explanation: "no sensor triggered"}
+	}
+	push := p.pushback(*closest)
+	// avoid jerk by normalizing to the desired magnitude of the user's input
+	v := in.Intent.Add(push).Normalize().Mul(in.Intent.Mag())
+	return Command{
+		Vector:      v,
+		Pushback:    push,
+		Intervened:  true,
+		Explanation: fmt.Sprintf("pushback from %s at %.2fm", closest.Name, closest.Meters),
+	}
+}
+
+// pushback returns a vector pointing away from the obstacle a sensor sees. the joystick and the sensors both
+// turn with the chair, so where the chair faces in the world doesn't matter.
+func (p *ClosestPushback) pushback(r Reading) Vector2D {
+	push, ok := p.Pushes[r.Name]
+	if !ok {
+		push = p.Default
+	}
+	ang := push.AngleDeg * math.Pi / 180.0
+	// scaling factor, ramps up linearly with proximity
+	scale := math.Min(1.0, math.Max(1.0-r.Meters/r.Threshold, 0.0))
+	return Vector2D{X: math.Cos(ang), Y: math.Sin(ang)}.Mul(push.Gain * scale)
+}
@@ -0,0 +1,111 @@
+package avoid
+
+// collision avoidance strategies: given what the user asks for, what the sensors see and what the chair is doing,
+// decide what the chair should actually be told. strategies only do the math, they know nothing about the screen
+// or the bus, so the same one can run in the demo, in a replay of a recorded session or on the gateway.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+// Reading is what one range sensor sees
+type Reading struct {
+	Name      string
+	AimDeg    float64  // direction the sensor points, relative to the chair. 90 is straight ahead, 135 front-left
+	Mount     Vector2D // where the sensor sits, meters from the chair's center, X to the right and Y forward
+	Meters    float64  // distance from the sensor to the closest obstacle, Threshold if there is none
+	Threshold float64  // the sensor ignores anything further away
+}
+
+// Obstacle returns where the sensor sees the obstacle, in meters from the chair's center like Mount
+func (r Reading) Obstacle() Vector2D {
+	a := r.AimDeg * math.Pi / 180
+	return r.Mount.Add(Vector2D{X: r.Meters * math.Cos(a), Y: r.Meters * math.Sin(a)})
+}
+
+// Triggered is true if the sensor sees an obstacle
+func (r Reading) Triggered() bool {
+	return r.Meters < r.Threshold
+}
+
+// State is what the chair is doing
+type State struct {
+	BearingDeg float64
+	Speed      int     // speed level, 0 through 4
+	Velocity   float64 // forward speed in m/s, negative when reversing
+	TurnRate   float64 // rad/s, positive turns left
+}
+
+type Input struct {
+	Intent   Vector2D // joystick position the user asks for, X is side and Y forward, as decoded by rnet.ConvertDataToJoy
+	Readings []Reading
+	Chair    State
+	Time     time.Time // when the readings were taken, zero if unknown
+}
+
+type Command struct {
+	Intent      Vector2D    // what the user asked for, filled in by Apply
+	Vector      Vector2D    // joystick position to send to the chair
+	Pushback    Vector2D    // what the strategy added to the intent, if that's how it works
+	Intervened  bool        // false if the intent is passed on untouched
+	Explanation string      // why, for the screen and the session recording
+	Limit       *SpeedLimit // the speed limit a Governor is enforcing, if any
+}
+
+// Strategy decides what to send to the chair
+type Strategy interface {
+	Name() string
+	Decide(in Input) Command
+}
+
+// strategies holds a constructor for every strategy, by name
+var strategies = map[string]func() Strategy{
+	CLOSEST_PUSHBACK:       func() Strategy { return NewClosestPushback() },
+	VECTOR_FIELD_HISTOGRAM: func() Strategy { return NewVFH() },
+	DYNAMIC_WINDOW:         func() Strategy { return NewDWA() },
+}
+
+// New returns the strategy with the given name, with its default settings
+func New(name string) (Strategy, error) {
+	mk, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q, expected one of %v", name, Names())
+	}
+	return mk(), nil
+}
+
+// Names lists the strategies New knows about
+func Names() []string {
+	var names []string
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply runs a movement frame through a strategy. The frame is returned as is if it's not a movement frame,
+// or if the strategy doesn't intervene, otherwise a copy with the commanded joystick position.
+func Apply(s Strategy, in Input, f *can.Frame) (*can.Frame, Command) {
+	if !rnet.IsMovementFrame(f.ID) || f.DLC < 2 {
+		return f, Command{}
+	}
+	fwd, side := rnet.ConvertDataToJoy(f.Data[0], f.Data[1])
+	in.Intent = Vector2D{X: side, Y: fwd}
+	cmd := s.Decide(in)
+	cmd.Intent = in.Intent
+	if !cmd.Intervened {
+		return f, cmd
+	}
+	// the inverse of rnet.ConvertDataToJoy, which flips the side axis
+	x, y := rnet.ConvertJoyToData(float32(rnet.INPUT_SCALE_SIDE*cmd.Vector.X), float32(rnet.INPUT_SCALE_FWD*cmd.Vector.Y))
+	out := *f
+	out.Data[0], out.Data[1] = uint8(x), uint8(y)
+	return &out, cmd
+}
@@ -0,0 +1,121 @@
+package avoid
+
+import (
+	"math"
+	"testing"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+// front returns readings of the demo's three front sensors
+func front(left, center, right float64) []Reading {
+	return []Reading{
+		{Name: "front-center", AimDeg: 90, Meters: center, Threshold: 25},
+		{Name: "front-left", AimDeg: 135, Meters: left, Threshold: 25},
+		{Name: "front-right", AimDeg: 45, Meters: right, Threshold: 25},
+	}
+}
+
+func TestReadingObstacle(t *testing.T) {
+	type test struct {
+		name string
+		r    Reading
+		want Vector2D
+	}
+	tests := []test{
+		{name: "center", r: Reading{AimDeg: 90, Meters: 1}, want: Vector2D{X: 0, Y: 1}},
+		{name: "front", r: Reading{AimDeg: 90, Mount: Vector2D{X: 0, Y: 0.6}, Meters: 1}, want: Vector2D{X: 0, Y: 1.6}},
+		{name: "front-left corner", r: Reading{AimDeg: 135, Mount: Vector2D{X: -0.3, Y: 0.6}, Meters: math.Sqrt2}, want: Vector2D{X: -1.3, Y: 1.6}},
+		{name: "right side", r: Reading{AimDeg: 0, Mount: Vector2D{X: 0.3, Y: 0}, Meters: 0.5}, want: Vector2D{X: 0.8, Y: 0}},
+	}
+	for _, test := range tests {
+		if got := test.r.Obstacle(); !got.Eq(test.want) {
+			t.Fatalf("%s: Obstacle(%+v), expected: %v, got: %v", test.name, test.r, test.want, got)
+		}
+	}
+}
+
+func TestClosestPushback(t *testing.T) {
+	type test struct {
+		name       string
+		in         Input
+		want       Vector2D
+		intervened bool
+	}
+	tests := []test{
+		{name: "clear", in: Input{Intent: Vector2D{X: 0, Y: 1}, Readings: front(25, 25, 25)}, want: Vector2D{X: 0, Y: 1}},
+		// pushed back by half of 0.5, then scaled back to the intent's length
+		{name: "wall ahead", in: Input{Intent: Vector2D{X: 0, Y: 1}, Readings: front(25, 12.5, 25)}, want: Vector2D{X: 0, Y: 1}, intervened: true},
+		{name: "wall ahead, backwards", in: Input{Intent: Vector2D{X: 0.5, Y: 0}, Readings: front(25, 12.5, 25)}, want: Vector2D{X: 0.5, Y: -0.25}.Normalize().Mul(0.5), intervened: true},
+		// the closest sensor wins, pushing right
+		{name: "left closest", in: Input{Intent: Vector2D{X: 0, Y: 1}, Readings: front(5, 20, 25)}, want: Vector2D{X: 1.6, Y: 1}.Normalize(), intervened: true},
+		// the pushback is in the chair's frame, the bearing doesn't turn it
+		{name: "bearing", in: Input{Intent: Vector2D{X: 0, Y: 1}, Readings: front(25, 25, 5), Chair: State{BearingDeg: 90}}, want: Vector2D{X: -1.6, Y: 1}.Normalize(), intervened: true},
+	}
+	p := NewClosestPushback()
+	for _, test := range tests {
+		got := p.Decide(test.in)
+		if !got.Vector.Eq(test.want) {
+			t.Fatalf("%s: Decide(%+v), expected: %v, got: %v (%s)", test.name, test.in, test.want, got.Vector, got.Explanation)
+		}
+		if got.Intervened != test.intervened {
+			t.Fatalf("%s: expected intervened %t, got: %+v", test.name, test.intervened, got)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	s, err := New(CLOSEST_PUSHBACK)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := New("teleport"); err == nil {
+		t.Fatalf("expected an error for an unknown strategy")
+	}
+
+	type test struct {
+		in        string
+		readings  []Reading
+		changed   bool
+		side, fwd float64 // of the frame sent on, decoded
+	}
+	tests := []test{
+		// untouched: not a movement frame, or nothing triggered
+		{in: "00E#048C1CBC00000000", readings: front(1, 1, 1)},
+		{in: "02000100#0A64", readings: front(25, 25, 25)},
+		// pushed right by the left sensor, with the side axis limited like every encoded frame
+		{in: "02000100#0064", readings: front(5, 20, 25), changed: true, side: 0.4, fwd: 0.53},
+		// and left by the right one
+		{in: "02000100#0064", readings: front(25, 20, 5), changed: true, side: -0.4, fwd: 0.53},
+	}
+	for _, test := range tests {
+		f, _ := can.FromLog(test.in)
+		got, cmd := Apply(s, Input{Readings: test.readings}, f)
+		if !test.changed {
+			if got != f {
+				t.Fatalf("Apply(%s), expected: the frame untouched, got: %s (%+v)", test.in, got, cmd)
+			}
+			continue
+		}
+		fwd, side := rnet.ConvertDataToJoy(got.Data[0], got.Data[1])
+		if math.Abs(side-test.side) > 0.005 || math.Abs(fwd-test.fwd) > 0.005 {
+			t.Fatalf("Apply(%s), expected: side %.2f fwd %.2f, got: side %.2f fwd %.2f (%s, %+v)", test.in, test.side, test.fwd, side, fwd, got, cmd)
+		}
+	}
+
+	// a strategy that passes the intent on, but says it intervened, must send the same joystick position
+	f, _ := can.FromLog("02000100#E232")
+	got, _ := Apply(echo{}, Input{}, f)
+	if got.Data != f.Data {
+		t.Fatalf("Apply(%s) through an echo, expected: %s, got: %s", f, f, got)
+	}
+}
+
+// echo intervenes without changing anything
+type echo struct{}
+
+func (echo) Name() string { return "echo" }
+func (echo) Decide(in Input) Command {
+	return Command{Vector: in.Intent, Intervened: true}
+}
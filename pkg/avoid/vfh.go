@@ -0,0 +1,200 @@
+package avoid
+
+// vector field histogram, after Borenstein & Koren, "The Vector Field Histogram - Fast Obstacle Avoidance for Mobile Robots" (1991).
+// every range reading adds to the obstacle density of the sectors around the chair it covers, the closer the obstacle
+// the more. sectors over a threshold are blocked, runs of free sectors are valleys. if the direction the user asks for
+// is blocked, the chair is steered into the nearest valley instead, and it slows down the closer it gets to obstacles
+// near its path. unlike the closest-sensor pushback, every sensor counts, so dodging one obstacle doesn't steer the
+// chair into another, and a corridor with walls on both sides doesn't make it bounce between them.
+
+import (
+	"fmt"
+	"math"
+)
+
+const VECTOR_FIELD_HISTOGRAM = "vfh"
+
+type VFH struct {
+	SectorDeg   float64 // width of a histogram sector
+	BeamDeg     float64 // field of view of a sensor, a reading covers its aim +/- half of this
+	RobotRadius float64 // meters, obstacles are widened by this so the whole chair fits through a valley
+	Threshold   float64 // a sector with a density over this is blocked, densities go from 0 (nothing) to 1 (touching)
+	WideValley  int     // sectors. in a valley wider than this, steer this/2 sectors from its edge rather than to its middle
+	MinSpeed    float64 // fraction of the requested speed the chair keeps next to an obstacle, as long as its direction is free
+	MaxSteerDeg float64 // never steer further than this from the requested direction, stop instead. we can't see behind the chair
+}
+
+func NewVFH() *VFH {
+	return &VFH{
+		SectorDeg:   5,
+		BeamDeg:     30,
+		RobotRadius: CHAIR_WIDTH_METERS/2 + 0.1, // plus a 10 cm margin
+		Threshold:   0.1,
+		WideValley:  8,
+		MinSpeed:    0.2,
+		MaxSteerDeg: 90,
+	}
+}
+
+func (v *VFH) Name() string {
+	return VECTOR_FIELD_HISTOGRAM
+}
+
+func (v *VFH) sectors() int {
+	return int(math.Ceil(360 / v.SectorDeg))
+}
+
+// sector returns the histogram sector of a direction in degrees
+func (v *VFH) sector(deg float64) int {
+	n := v.sectors()
+	return ((int(math.Floor(deg/v.SectorDeg)) % n) + n) % n
+}
+
+// direction returns the center of a sector in degrees
+func (v *VFH) direction(k int) float64 {
+	return (float64(k) + 0.5) * v.SectorDeg
+}
+
+// Histogram returns the polar obstacle density around the chair, sector k covers k*SectorDeg to (k+1)*SectorDeg
+func (v *VFH) Histogram(readings []Reading) []float64 {
+	h := make([]float64, v.sectors())
+	for _, r := range readings {
+		if !r.Triggered() || r.Threshold <= 0 {
+			continue
+		}
+		m := math.Pow(1-math.Max(r.Meters, 0)/r.Threshold, 2)
+		// the histogram is around the chair's center, a sensor off to the side sees the obstacle from somewhere else
+		o := r.Obstacle()
+		aim, dist := math.Atan2(o.Y, o.X)*180/math.Pi, o.Mag()
+		// the chair needs room on both sides, so closer obstacles cover a wider angle
+		widen := 90.0
+		if dist > v.RobotRadius {
+			widen = math.Asin(v.RobotRadius/dist) * 180 / math.Pi
+		}
+		half := v.BeamDeg/2 + widen
+		for k := v.sector(aim - half); ; k = (k + 1) % len(h) {
+			h[k] = math.Max(h[k], m)
+			if k == v.sector(aim+half) {
+				break
+			}
+		}
+	}
+	return h
+}
+
+// valley is a run of free sectors, from first to last going counterclockwise
+type valley struct {
+	first, last int
+}
+
+func (v *VFH) valleys(blocked []bool) []valley {
+	n := len(blocked)
+	start := -1
+	for k := range blocked {
+		if blocked[k] {
+			start = k
+			break
+		}
+	}
+	if start < 0 {
+		return []valley{{0, n - 1}}
+	}
+	// walk once around the circle from a blocked sector, so no valley is split in two
+	var vs []valley
+	in := false
+	for i := 1; i <= n; i++ {
+		k := (start + i) % n
+		switch {
+		case !blocked[k] && !in:
+			vs = append(vs, valley{first: k, last: k})
+			in = true
+		case !blocked[k]:
+			vs[len(vs)-1].last = k
+		default:
+			in = false
+		}
+	}
+	return vs
+}
+
+// width returns the number of sectors in the valley
+func (vl valley) width(n int) int {
+	return (vl.last-vl.first+n)%n + 1
+}
+
+// candidates returns the sectors worth steering to in a valley
+func (v *VFH) candidates(vl valley, n int) []int {
+	w := vl.width(n)
+	if w <= v.WideValley {
+		return []int{(vl.first + w/2) % n}
+	}
+	return []int{(vl.first + v.WideValley/2) % n, (vl.last - v.WideValley/2 + n) % n}
+}
+
+// angleBetween returns the smallest angle between two directions in degrees
+func angleBetween(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	return math.Min(d, 360-d)
+}
+
+func (v *VFH) Decide(in Input) Command {
+	mag := in.Intent.Mag()
+	if mag == 0 {
+		return Command{Vector: in.Intent, Explanation: "no input"}
+	}
+	h := v.Histogram(in.Readings)
+	n := len(h)
+	blocked := make([]bool, n)
+	anything := false
+	for k := range h {
+		blocked[k] = h[k] > v.Threshold
+		anything = anything || h[k] > 0
+	}
+	if !anything {
+		return Command{Vector: in.Intent, Explanation: "no sensor triggered"}
+	}
+
+	desired := math.Atan2(in.Intent.Y, in.Intent.X) * 180 / math.Pi
+	if desired < 0 {
+		desired += 360
+	}
+	chosen := desired
+	reason := ""
+	if blocked[v.sector(desired)] {
+		best := -1
+		for _, vl := range v.valleys(blocked) {
+			for _, k := range v.candidates(vl, n) {
+				if angleBetween(v.direction(k), desired) > v.MaxSteerDeg {
+					continue
+				}
+				if best < 0 || angleBetween(v.direction(k), desired) < angleBetween(v.direction(best), desired) {
+					best = k
+				}
+			}
+		}
+		if best < 0 {
+			return Command{Vector: Vector2D{}, Pushback: in.Intent.Mul(-1), Intervened: true, Explanation: "no free valley nearby, stopping"}
+		}
+		chosen = v.direction(best)
+		reason = fmt.Sprintf("%.0f° blocked, steering to %.0f°, ", desired, chosen)
+	}
+
+	// slow down for the densest sector near the path, about as wide as the chair
+	near := 0.0
+	for i := -v.WideValley / 2; i <= v.WideValley/2; i++ {
+		near = math.Max(near, h[(v.sector(chosen)+i+n)%n])
+	}
+	speed := math.Max(v.MinSpeed, 1-near)
+	if chosen == desired && speed == 1 {
+		return Command{Vector: in.Intent, Explanation: "path clear"}
+	}
+
+	rad := chosen * math.Pi / 180
+	out := Vector2D{X: math.Cos(rad), Y: math.Sin(rad)}.Mul(mag * speed)
+	return Command{
+		Vector:      out,
+		Pushback:    out.Sub(in.Intent),
+		Intervened:  true,
+		Explanation: fmt.Sprintf("%sspeed %.0f%%", reason, 100*speed),
+	}
+}
@@ -0,0 +1,93 @@
+package avoid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVFH(t *testing.T) {
+	// meters, with the sensors seeing up to 2m
+	at := func(left, center, right float64) []Reading {
+		return []Reading{
+			{Name: "front-left", AimDeg: 135, Meters: left, Threshold: 2},
+			{Name: "front-center", AimDeg: 90, Meters: center, Threshold: 2},
+			{Name: "front-right", AimDeg: 45, Meters: right, Threshold: 2},
+		}
+	}
+	ahead := Vector2D{X: -0.05, Y: 1} // a touch to the left, so wall-ahead cases don't depend on tie breaking
+
+	type test struct {
+		name       string
+		intent     Vector2D
+		readings   []Reading
+		angle      float64 // of the commanded vector, degrees
+		speed      float64 // commanded length over intent length
+		intervened bool
+	}
+	tests := []test{
+		{name: "clear", intent: ahead, readings: at(2, 2, 2), angle: 92.9, speed: 1},
+		// the pushback would stop here, the corridor is wide enough to keep going, a little slower
+		{name: "corridor", intent: Vector2D{X: 0, Y: 1}, readings: at(1.6, 2, 1.6), angle: 90, speed: 0.96, intervened: true},
+		{name: "wall ahead", intent: ahead, readings: at(2, 1, 2), angle: 162.5, speed: 1, intervened: true},
+		// with the left blocked too, the way out is to the right rather than into the other obstacle
+		{name: "wall ahead and left", intent: ahead, readings: at(1, 1, 2), angle: 17.5, speed: 1, intervened: true},
+		{name: "boxed in", intent: ahead, readings: at(0.3, 0.3, 0.3), angle: 0, speed: 0, intervened: true},
+		// nothing behind the chair is seen, backing up is left alone
+		{name: "reverse", intent: Vector2D{X: 0, Y: -0.5}, readings: at(0.3, 0.3, 0.3), angle: 270, speed: 1},
+	}
+	v := NewVFH()
+	for _, test := range tests {
+		got := v.Decide(Input{Intent: test.intent, Readings: test.readings})
+		angle := math.Mod(math.Atan2(got.Vector.Y, got.Vector.X)*180/math.Pi+360, 360)
+		speed := got.Vector.Mag() / test.intent.Mag()
+		if got.Intervened != test.intervened || math.Abs(speed-test.speed) > 0.01 || (speed > 0 && math.Abs(angle-test.angle) > 0.1) {
+			t.Fatalf("%s: Decide(%v), expected: %.1f° at %.2f (intervened %t), got: %.1f° at %.2f (%+v)",
+				test.name, test.intent, test.angle, test.speed, test.intervened, angle, speed, got)
+		}
+	}
+}
+
+func TestVFHHistogram(t *testing.T) {
+	v := NewVFH()
+	h := v.Histogram([]Reading{
+		{AimDeg: 90, Meters: 1, Threshold: 2},
+		{AimDeg: 0, Meters: 2, Threshold: 2}, // not triggered
+	})
+	if len(h) != 72 {
+		t.Fatalf("expected 72 sectors, got: %d", len(h))
+	}
+	for k, d := range h {
+		// 90° +/- (15° beam + 32° for the chair's width)
+		want := 0.0
+		if k >= 8 && k <= 27 {
+			want = 0.25
+		}
+		if d != want {
+			t.Fatalf("sector %d (%.1f°), expected: %.2f, got: %.2f", k, v.direction(k), want, d)
+		}
+	}
+}
+
+// a sensor off the chair's center sees obstacles from where it's mounted, the histogram is around the center
+func TestVFHHistogramMount(t *testing.T) {
+	v := NewVFH()
+	r := Reading{AimDeg: 90, Mount: Vector2D{X: 0.35, Y: 0.6}, Meters: 1, Threshold: 2}
+	h := v.Histogram([]Reading{r})
+	o := r.Obstacle()
+	want := math.Atan2(o.Y, o.X) * 180 / math.Pi
+	first, last := -1, -1
+	for k, d := range h {
+		if d > 0 {
+			if first < 0 {
+				first = k
+			}
+			last = k
+		}
+	}
+	if first < 0 || h[v.sector(want)] != 0.25 {
+		t.Fatalf("Histogram(%+v), expected: 0.25 at %.1f°, got: %v", r, want, h)
+	}
+	if got := (v.direction(first) + v.direction(last)) / 2; math.Abs(got-want) > v.SectorDeg {
+		t.Fatalf("Histogram(%+v), expected: centered on %.1f°, got: %.1f°", r, want, got)
+	}
+}
@@ -0,0 +1,209 @@
+package bussim
+
+// simulates the background R-Net traffic produced by everything on the bus that is not the joystick module,
+// so that jsmbuffer and friends can be tested on vcan or an in-memory can.Bus without the chair.
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+// Message is a frame emitted periodically by a simulated node
+type Message struct {
+	Name   string
+	Frame  can.Frame
+	Period time.Duration
+	Jitter time.Duration // each period is randomly lengthened or shortened by up to this much
+}
+
+// DefaultMessages returns the periodic traffic we captured from the chair with the JSM idle (see docs/JSM_*.csv).
+// The captures are only ~100ms long, so the periods of the slower messages are estimates.
+func DefaultMessages() []Message {
+	return []Message{
+		{
+			// seen every 50ms in JSM_idle.csv
+			Name:   "status",
+			Frame:  can.Frame{ID: 0x00E, DLC: 8, Data: [8]uint8{0x04, 0x8C, 0x1C, 0xBC, 0x00, 0x00, 0x00, 0x00}},
+			Period: 50 * time.Millisecond,
+			Jitter: 500 * time.Microsecond,
+		},
+		{
+			// seen once per capture in every file
+			Name:   "3C30F0F",
+			Frame:  can.Frame{ID: 0x03C30F0F | can.CAN_EFF_FLAG, DLC: 7, Data: [8]uint8{0x87, 0x87, 0x87, 0x87, 0x87, 0x87, 0x87}},
+			Period: 100 * time.Millisecond,
+			Jitter: 1 * time.Millisecond,
+		},
+		{
+			// seen once in JSM_idle.csv
+			Name:   "140C0001",
+			Frame:  can.Frame{ID: 0x140C0001 | can.CAN_EFF_FLAG, DLC: 2},
+			Period: 100 * time.Millisecond,
+			Jitter: 1 * time.Millisecond,
+		},
+	}
+}
+
+// fault holds the misbehaviour currently scripted for a message
+type fault struct {
+	silentUntil time.Time
+	corrupt     int // number of upcoming frames whose payload gets scrambled
+}
+
+type Simulator struct {
+	iface    can.Interface
+	messages []Message
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	faults map[string]*fault
+}
+
+// New creates a simulator sending messages on iface. The seed makes jitter and corruption reproducible.
+func New(iface can.Interface, messages []Message, seed int64) *Simulator {
+	return &Simulator{
+		iface:    iface,
+		messages: messages,
+		rng:      rand.New(rand.NewSource(seed)),
+		faults:   make(map[string]*fault),
+	}
+}
+
+// Run sends the periodic traffic and answers remote frames until ctx is cancelled.
+// The caller should close the interface afterwards to release the goroutine blocked reading from it.
+func (s *Simulator) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := range s.messages {
+		wg.Add(1)
+		go func(m *Message) {
+			defer wg.Done()
+			s.transmit(ctx, m)
+		}(&s.messages[i])
+	}
+	go s.respond(ctx)
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *Simulator) transmit(ctx context.Context, m *Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.nextPeriod(m)):
+		}
+		s.send(m)
+	}
+}
+
+// respond answers remote transmission requests for any message we simulate
+func (s *Simulator) respond(ctx context.Context) {
+	for {
+		f, err := s.iface.Read()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[bussim] read error: %v", err)
+			}
+			return
+		}
+		if !f.IsRemote() {
+			continue
+		}
+		for i := range s.messages {
+			m := &s.messages[i]
+			if m.Frame.ArbID() == f.ArbID() && m.Frame.IsExtended() == f.IsExtended() {
+				s.send(m)
+			}
+		}
+	}
+}
+
+func (s *Simulator) nextPeriod(m *Message) time.Duration {
+	if m.Jitter <= 0 {
+		return m.Period
+	}
+	s.mu.Lock()
+	j := time.Duration(s.rng.Int63n(int64(2*m.Jitter))) - m.Jitter
+	s.mu.Unlock()
+	return m.Period + j
+}
+
+func (s *Simulator) send(m *Message) {
+	f := m.Frame
+	s.mu.Lock()
+	if flt, ok := s.faults[m.Name]; ok {
+		if time.Now().Before(flt.silentUntil) {
+			s.mu.Unlock()
+			return
+		}
+		if flt.corrupt > 0 {
+			flt.corrupt--
+			for i := 0; i < int(f.DLC); i++ {
+				// xor with a non-zero value so every byte is guaranteed to change
+				f.Data[i] ^= uint8(1 + s.rng.Intn(255))
+			}
+		}
+	}
+	s.mu.Unlock()
+	if err := s.iface.Send(&f); err != nil {
+		log.Printf("[bussim] send %s error: %v", m.Name, err)
+	}
+}
+
+func (s *Simulator) fault(name string) (*fault, error) {
+	for _, m := range s.messages {
+		if m.Name == name {
+			if _, ok := s.faults[name]; !ok {
+				s.faults[name] = &fault{}
+			}
+			return s.faults[name], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown message %q", name)
+}
+
+// Silence stops a message from being sent (and answering remote frames) for d, as if its node went missing
+func (s *Simulator) Silence(name string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flt, err := s.fault(name)
+	if err != nil {
+		return err
+	}
+	flt.silentUntil = time.Now().Add(d)
+	return nil
+}
+
+// Corrupt scrambles the payload of the next n frames of a message
+func (s *Simulator) Corrupt(name string, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flt, err := s.fault(name)
+	if err != nil {
+		return err
+	}
+	flt.corrupt = n
+	return nil
+}
+
+// Burst sends count copies of f, gap apart, e.g. to flood the bus
+func (s *Simulator) Burst(ctx context.Context, f can.Frame, count int, gap time.Duration) error {
+	for i := 0; i < count; i++ {
+		c := f
+		if err := s.iface.Send(&c); err != nil {
+			return fmt.Errorf("burst frame %d: %w", i, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(gap):
+		}
+	}
+	return nil
+}
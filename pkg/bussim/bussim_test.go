@@ -0,0 +1,118 @@
+package bussim
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+func TestSimulatorRespondsToRemoteFrames(t *testing.T) {
+	bus := can.NewBus()
+	simNode, testNode := bus.Connect(), bus.Connect()
+	defer testNode.Close()
+
+	// a period long enough that anything we read is a reply to our request
+	msgs := []Message{{
+		Name:   "status",
+		Frame:  can.Frame{ID: 0x00E, DLC: 2, Data: [8]uint8{0x12, 0x34}},
+		Period: time.Hour,
+	}}
+	sim := New(simNode, msgs, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sim.Run(ctx)
+	defer simNode.Close()
+
+	err := testNode.Send(&can.Frame{ID: 0x00E | can.CAN_RTR_FLAG, DLC: 2})
+	if err != nil {
+		t.Fatalf("failed to send remote frame: %v", err)
+	}
+	f, err := testNode.Read()
+	if err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+	if f.String() != "00E#1234" {
+		t.Fatalf("expected reply 00E#1234, got: %s", f)
+	}
+}
+
+func TestSimulatorFaults(t *testing.T) {
+	bus := can.NewBus()
+	simNode, testNode := bus.Connect(), bus.Connect()
+	defer testNode.Close()
+	defer simNode.Close()
+
+	msgs := []Message{{
+		Name:  "status",
+		Frame: can.Frame{ID: 0x00E, DLC: 2, Data: [8]uint8{0x12, 0x34}},
+	}}
+	sim := New(simNode, msgs, 1)
+
+	if err := sim.Corrupt("status", 1); err != nil {
+		t.Fatalf("failed to corrupt: %v", err)
+	}
+	sim.send(&sim.messages[0])
+	sim.send(&sim.messages[0])
+	f, _ := testNode.Read()
+	if f.Data[0] == 0x12 || f.Data[1] == 0x34 {
+		t.Fatalf("expected corrupted payload, got: %s", f)
+	}
+	f, _ = testNode.Read()
+	if f.String() != "00E#1234" {
+		t.Fatalf("expected intact payload after corruption, got: %s", f)
+	}
+
+	if err := sim.Silence("status", time.Hour); err != nil {
+		t.Fatalf("failed to silence: %v", err)
+	}
+	sim.send(&sim.messages[0])
+	got := make(chan *can.Frame, 1)
+	go func() {
+		f, _ := testNode.Read()
+		got <- f
+	}()
+	select {
+	case f := <-got:
+		t.Fatalf("expected silenced message not to be sent, got: %s", f)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := sim.Silence("nope", time.Second); err == nil {
+		t.Fatalf("expected error silencing unknown message")
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	type test struct {
+		input   string
+		want    int
+		wantErr bool
+	}
+
+	tests := []test{
+		{input: "# nothing to do\n\n", want: 0},
+		{input: "2s silence status 1s\n1s corrupt status 3 # comment", want: 2},
+		{input: "0s burst 02000100#0000 5 1ms", want: 1},
+		{input: "1s explode status", wantErr: true},
+		{input: "soon silence status 1s", wantErr: true},
+		{input: "1s burst 02000100#000 5 1ms", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseScript(strings.NewReader(test.input))
+		if (err != nil) != test.wantErr {
+			t.Fatalf("ParseScript(%q), expected error: %t, got: %v", test.input, test.wantErr, err)
+		}
+		if len(got) != test.want {
+			t.Fatalf("ParseScript(%q), expected: %d steps, got: %d", test.input, test.want, len(got))
+		}
+	}
+
+	script, _ := ParseScript(strings.NewReader("2s silence status 1s\n1s corrupt status 3"))
+	if script[0].At != time.Second {
+		t.Fatalf("expected steps sorted by offset, got: %v first", script[0].At)
+	}
+}
@@ -0,0 +1,138 @@
+package bussim
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+// Step is a single fault injected at a given offset from the start of a script
+type Step struct {
+	At   time.Duration
+	Line string // the original script line, for logging
+	run  func(ctx context.Context, s *Simulator) error
+}
+
+// Script is a list of steps, sorted by offset
+type Script []Step
+
+// ParseScript reads a fault script. Each line holds an offset followed by an action:
+//
+//	# comments and blank lines are ignored
+//	2s    silence status 1s              # drop a message for a while, as if its node went missing
+//	4s    corrupt 3C30F0F 3              # scramble the payload of the next 3 frames
+//	6s    burst 02000100#0000 50 1ms     # send a frame 50 times, 1ms apart
+//
+// Messages are referred to by their Message.Name.
+func ParseScript(r io.Reader) (Script, error) {
+	var script Script
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			// strip comments, but not the '#' separating id and data in a frame
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		step, err := parseStep(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		step.Line = strings.TrimSpace(line)
+		script = append(script, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(script, func(i, j int) bool { return script[i].At < script[j].At })
+	return script, nil
+}
+
+func parseStep(fields []string) (Step, error) {
+	if len(fields) < 2 {
+		return Step{}, fmt.Errorf("expected '<offset> <action> [args...]'")
+	}
+	at, err := time.ParseDuration(fields[0])
+	if err != nil {
+		return Step{}, fmt.Errorf("invalid offset: %w", err)
+	}
+	action, args := fields[1], fields[2:]
+	step := Step{At: at}
+	switch action {
+	case "silence":
+		if len(args) != 2 {
+			return Step{}, fmt.Errorf("usage: silence <message> <duration>")
+		}
+		d, err := time.ParseDuration(args[1])
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid duration: %w", err)
+		}
+		step.run = func(ctx context.Context, s *Simulator) error {
+			return s.Silence(args[0], d)
+		}
+	case "corrupt":
+		if len(args) != 2 {
+			return Step{}, fmt.Errorf("usage: corrupt <message> <count>")
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid count: %w", err)
+		}
+		step.run = func(ctx context.Context, s *Simulator) error {
+			return s.Corrupt(args[0], count)
+		}
+	case "burst":
+		if len(args) != 3 {
+			return Step{}, fmt.Errorf("usage: burst <frame> <count> <gap>")
+		}
+		f, err := can.FromLog(args[0])
+		if err != nil {
+			return Step{}, err
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid count: %w", err)
+		}
+		gap, err := time.ParseDuration(args[2])
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid gap: %w", err)
+		}
+		step.run = func(ctx context.Context, s *Simulator) error {
+			return s.Burst(ctx, *f, count, gap)
+		}
+	default:
+		return Step{}, fmt.Errorf("unknown action %q", action)
+	}
+	return step, nil
+}
+
+// Play runs the steps of a script at their offsets from now. Bursts run in the background
+// so that they do not delay later steps.
+func (s *Simulator) Play(ctx context.Context, script Script) error {
+	start := time.Now()
+	for _, step := range script {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(step.At))):
+		}
+		go func(step Step) {
+			if err := step.run(ctx, s); err != nil && ctx.Err() == nil {
+				// keep going, the rest of the script should still play
+				log.Printf("[bussim] %q failed: %v", step.Line, err)
+			}
+		}(step)
+	}
+	return nil
+}
@@ -32,13 +32,59 @@ type Frame struct {
 const (
 	FRAME_MAX_SIZE int = 16 // 16-byte maximum frame size
 
-	CAN_EFF_FLAG = 0x80000000
+	CAN_EFF_FLAG = 0x80000000 // extended frame format (29-bit id)
+	CAN_RTR_FLAG = 0x40000000 // remote transmission request
+	CAN_ERR_FLAG = 0x20000000 // error message frame
+
+	CAN_SFF_MASK = 0x000007FF // standard frame format id bits
+	CAN_EFF_MASK = 0x1FFFFFFF // extended frame format id bits
 )
 
+// Interface is anything we can exchange frames with, e.g. a Socket or a node on an in-memory Bus.
+type Interface interface {
+	Send(f *Frame) error
+	Read() (*Frame, error)
+}
+
 func (f Frame) Payload() []byte {
 	return f.Data[:f.DLC]
 }
 
+// IsExtended tells us whether the frame uses a 29-bit id
+func (f Frame) IsExtended() bool {
+	return f.ID&CAN_EFF_FLAG != 0
+}
+
+// IsRemote tells us whether the frame is a remote transmission request
+func (f Frame) IsRemote() bool {
+	return f.ID&CAN_RTR_FLAG != 0
+}
+
+// ArbID returns the arbitration id without the EFF/RTR/ERR flags
+func (f Frame) ArbID() uint32 {
+	if f.IsExtended() {
+		return f.ID & CAN_EFF_MASK
+	}
+	return f.ID & CAN_SFF_MASK
+}
+
+// String returns the frame in the candump log format accepted by FromLog, e.g. "02000100#0064".
+// Remote frames are written as "<can_id>#R{len}".
+func (f Frame) String() string {
+	id := fmt.Sprintf("%03X", f.ArbID())
+	if f.IsExtended() {
+		id = fmt.Sprintf("%08X", f.ArbID())
+	}
+	if f.IsRemote() {
+		return fmt.Sprintf("%s#R%d", id, f.DLC)
+	}
+	dlc := f.DLC
+	if dlc > 8 {
+		dlc = 8
+	}
+	return id + "#" + strings.ToUpper(hex.EncodeToString(f.Data[:dlc]))
+}
+
 // use candump from can-utils log format
 // Usage: cansend <device> <can_frame>.
 
@@ -56,25 +102,39 @@ func (f Frame) Payload() []byte {
 // <flags>:
 //  a single ASCII Hex value (0 .. F) which defines canfd_frame.flags
 func FromLog(logline string) (*Frame, error) {
-	if strings.Contains(logline, "R") {
-		return nil, fmt.Errorf("invalid input: 'R' not supported")
-	}
 	parts := strings.Split(logline, "#")
-	if len(parts) < 2 {
+	if len(parts) != 2 {
 		return nil, fmt.Errorf("invalid input: expected single # separator")
 	}
 	if len(parts[0]) != 3 && len(parts[0]) != 8 {
 		return nil, fmt.Errorf("invalid input: id expected to be 3 or 8 hex chars")
 	}
+	isRemote := strings.HasPrefix(parts[1], "R")
+	if isRemote {
+		// remote frames carry no data, just an optional length
+		dlc := 0
+		if len(parts[1]) > 2 || (len(parts[1]) == 2 && (parts[1][1] < '0' || parts[1][1] > '8')) {
+			return nil, fmt.Errorf("invalid input: remote frame length expected to be 0..8")
+		}
+		if len(parts[1]) == 2 {
+			dlc = int(parts[1][1] - '0')
+		}
+		// reuse the id parsing below with some dummy data
+		f, err := FromLog(parts[0] + "#00")
+		if err != nil {
+			return nil, err
+		}
+		f.ID |= CAN_RTR_FLAG
+		f.DLC = uint8(dlc)
+		f.Data = [8]uint8{}
+		return f, nil
+	}
 	if len(parts[1])%2 != 0 {
 		return nil, fmt.Errorf("invalid input: data expected to be even-numbered hex chars")
 	}
 	if len(parts[1]) > 16 {
 		return nil, fmt.Errorf("invalid input: max data length is 16 hex chars")
 	}
-	if len(parts[1]) < 2 {
-		return nil, fmt.Errorf("invalid input: missing data")
-	}
 	isExtendedID := len(parts[0]) == 8
 	if len(parts[0]) == 3 {
 		// make id 8 hex chars long for convenience
@@ -86,7 +146,12 @@ func FromLog(logline string) (*Frame, error) {
 	}
 	id := binary.BigEndian.Uint32(idBytes)
 	if isExtendedID {
+		if id > CAN_EFF_MASK {
+			return nil, fmt.Errorf("invalid input: extended id is limited to 29 bits")
+		}
 		id = id | CAN_EFF_FLAG
+	} else if id > CAN_SFF_MASK {
+		return nil, fmt.Errorf("invalid input: standard id is limited to 11 bits")
 	}
 
 	dataBytes, err := hex.DecodeString(parts[1])
@@ -1,21 +1,29 @@
-// go:build linux
+//go:build linux
+// +build linux
 
 package can
 
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/hex"
 	"fmt"
-	"log"
 	"net"
-	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
 
+// closing a socket doesn't wake up a read blocked on it, so reads give up every READ_TIMEOUT to check whether
+// the socket was closed in the meantime. Close can take that long to return.
+const READ_TIMEOUT = 100 * time.Millisecond
+
 type Socket struct {
 	fd int
+
+	// held for reading by Send and each wait in Read, so Close can't pull the fd out from under them
+	mu     sync.RWMutex
+	closed bool
 }
 
 func NewSocketBoundTo(iface string) (*Socket, error) {
@@ -30,18 +38,24 @@ func NewSocketBoundTo(iface string) (*Socket, error) {
 func (s *Socket) BindToInterface(name string) error {
 	i, err := net.InterfaceByName(name)
 	if err != nil {
-		log.Fatalf("failed to get %q: %v", name, err)
+		return fmt.Errorf("failed to get %q: %w", name, err)
 	}
 
 	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
 	if err != nil {
-		log.Fatalf("failed to get socket: %v", err)
+		return fmt.Errorf("failed to get socket: %w", err)
 	}
 
 	// see also https://pkg.go.dev/golang.org/x/sys/unix#SockaddrCAN
 	sa := &unix.SockaddrCAN{Ifindex: i.Index}
 	if err := unix.Bind(fd, sa); err != nil {
-		log.Fatalf("failed to bind socket: %v", err)
+		unix.Close(fd)
+		return fmt.Errorf("failed to bind socket: %w", err)
+	}
+	tv := unix.NsecToTimeval(READ_TIMEOUT.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("failed to set read timeout: %w", err)
 	}
 	s.fd = fd
 	return nil
@@ -56,25 +70,60 @@ func (s *Socket) Send(f *Frame) error {
 	if buf.Len() > int(FRAME_MAX_SIZE) {
 		return fmt.Errorf("frame too big. 16-byte maximum")
 	}
-	n, err := unix.Write(s.fd, buf.Bytes())
+	// note: no logging per frame, at 100 frames per second it adds measurable latency
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrClosed
+	}
+	_, err = unix.Write(s.fd, buf.Bytes())
 	if err != nil {
 		return fmt.Errorf("failed to write: %w", err)
 	}
-	log.Printf("wrote %d bytes to fd %d", n, s.fd)
 	return nil
 }
 
+// Read blocks until a frame arrives, or the socket is closed
 func (s *Socket) Read() (*Frame, error) {
 	buf := make([]byte, FRAME_MAX_SIZE)
-	n, err := unix.Read(s.fd, buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read %d bytes: %w", n, err)
+	for {
+		n, err := s.read(buf)
+		if err == unix.EAGAIN || err == unix.EINTR {
+			continue
+		}
+		if err == ErrClosed {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %d bytes: %w", n, err)
+		}
+		break
 	}
 	msg := &Frame{}
-	err = binary.Read(bytes.NewReader(buf), binary.LittleEndian, msg)
+	err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, msg)
 	if err != nil {
 		return nil, fmt.Errorf("unable to decode frame: %w", err)
 	}
-	log.Printf("read frame from %d", s.fd)
 	return msg, nil
 }
+
+// read waits up to READ_TIMEOUT for a frame
+func (s *Socket) read(buf []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+	return unix.Read(s.fd, buf)
+}
+
+// Close closes the socket once any pending Read gives up waiting, which then returns ErrClosed
+func (s *Socket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	return unix.Close(s.fd)
+}
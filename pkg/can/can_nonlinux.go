@@ -1,24 +1,51 @@
-// go:build !linux
+//go:build !linux
+// +build !linux
 
 package can
 
+import "sync"
+
 // Socket is a virtual (in-memory) socket since we do not have the vcan kernel module available
 type Socket struct {
-	data chan *Frame
+	data   chan *Frame
+	done   chan struct{}
+	closed sync.Once
 }
 
 func NewSocketBoundTo(iface string) (*Socket, error) {
 	return &Socket{
 		data: make(chan *Frame),
+		done: make(chan struct{}),
 	}, nil
 }
 
+// Send blocks until the frame is read, or the socket is closed
 func (s *Socket) Send(f *Frame) error {
-	s.data <- f
-	return nil
+	select {
+	case <-s.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case s.data <- f:
+		return nil
+	case <-s.done:
+		return ErrClosed
+	}
 }
 
+// Read blocks until a frame is sent, or the socket is closed
 func (s *Socket) Read() (*Frame, error) {
-	f := <-s.data
-	return f, nil
+	select {
+	case f := <-s.data:
+		return f, nil
+	case <-s.done:
+		return nil, ErrClosed
+	}
+}
+
+// Close unblocks any pending Send or Read, it is safe to call more than once
+func (s *Socket) Close() error {
+	s.closed.Do(func() { close(s.done) })
+	return nil
 }
@@ -0,0 +1,43 @@
+//go:build !linux
+// +build !linux
+
+package can
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSocketClose(t *testing.T) {
+	s, err := NewSocketBoundTo("vcan0")
+	if err != nil {
+		t.Fatalf("NewSocketBoundTo: %v", err)
+	}
+	read := make(chan error)
+	go func() {
+		_, err := s.Read()
+		read <- err
+	}()
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	select {
+	case err := <-read:
+		if err != ErrClosed {
+			t.Fatalf("expected a pending Read to return ErrClosed, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected Close to unblock a pending Read")
+	}
+
+	f, _ := FromLog("02000100#0A64")
+	if err := s.Send(f); err != ErrClosed {
+		t.Fatalf("expected Send after Close to return ErrClosed, got: %v", err)
+	}
+	if _, err := s.Read(); err != ErrClosed {
+		t.Fatalf("expected Read after Close to return ErrClosed, got: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("expected a second Close to be harmless, got: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package can
+
+import (
+	"testing"
+)
+
+func TestFromLog(t *testing.T) {
+	type test struct {
+		input   string
+		want    Frame
+		wantErr bool
+	}
+
+	tests := []test{
+		{input: "123#01020304050607", want: Frame{ID: 0x123, DLC: 7, Data: [8]uint8{1, 2, 3, 4, 5, 6, 7}}},
+		{input: "000#0000", want: Frame{ID: 0x000, DLC: 2}},
+		{input: "02000100#649C", want: Frame{ID: 0x02000100 | CAN_EFF_FLAG, DLC: 2, Data: [8]uint8{0x64, 0x9C}}},
+		{input: "7FF#00", want: Frame{ID: 0x7FF, DLC: 1}},
+		{input: "1FFFFFFF#00", want: Frame{ID: 0x1FFFFFFF | CAN_EFF_FLAG, DLC: 1}},
+		{input: "800#00", wantErr: true},      // more than 11 bits
+		{input: "20000000#00", wantErr: true}, // more than 29 bits
+		{input: "123#R", want: Frame{ID: 0x123 | CAN_RTR_FLAG}},
+		{input: "0A060000#R1", want: Frame{ID: 0x0A060000 | CAN_EFF_FLAG | CAN_RTR_FLAG, DLC: 1}},
+		{input: "123#R9", wantErr: true},
+		{input: "123#R00", wantErr: true},
+		{input: "123", wantErr: true},     // missing separator
+		{input: "1234#00", wantErr: true}, // bad id length
+		{input: "123#000", wantErr: true}, // odd data length
+		{input: "123#", want: Frame{ID: 0x123}},
+		{input: "123#00112233445566778", wantErr: true},
+		{input: "12G#00", wantErr: true},
+		{input: "123#0G", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := FromLog(test.input)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("FromLog(%q), expected error: %t, got: %v", test.input, test.wantErr, err)
+		}
+		if err == nil && *got != test.want {
+			t.Fatalf("FromLog(%q), expected: %+v, got: %+v", test.input, test.want, *got)
+		}
+	}
+}
+
+func FuzzFromLog(f *testing.F) {
+	for _, seed := range []string{
+		"123#01020304050607",
+		"000#0000",
+		"02000100#0064",
+		"02000100#9C64",
+		"00E#048C1CBC00000000",
+		"03C30F0F#87878787878787",
+		"0A060000#R1",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		fr, err := FromLog(line)
+		if err != nil {
+			return
+		}
+		if fr.DLC > 8 {
+			t.Fatalf("FromLog(%q) has invalid DLC %d", line, fr.DLC)
+		}
+		if fr.ID&CAN_ERR_FLAG != 0 {
+			t.Fatalf("FromLog(%q) = 0x%.8x, unexpected flags set", line, fr.ID)
+		}
+		// writing the frame back out and parsing it again gives us the same frame
+		again, err := FromLog(fr.String())
+		if err != nil {
+			t.Fatalf("FromLog(%q) = %s, which does not parse: %v", line, fr, err)
+		}
+		if *again != *fr {
+			t.Fatalf("round trip %q -> %+v -> %q -> %+v", line, *fr, fr.String(), *again)
+		}
+	})
+}
+
+func TestParseLogEntry(t *testing.T) {
+	line := "(1634567890.012345) vcan0 02000100#0064"
+	e, err := ParseLogEntry(line)
+	if err != nil {
+		t.Fatalf("ParseLogEntry(%q): %v", line, err)
+	}
+	if e.Iface != "vcan0" || e.Frame.String() != "02000100#0064" || e.Time.UnixNano() != 1634567890012345000 {
+		t.Fatalf("ParseLogEntry(%q), got: %+v", line, e)
+	}
+	if e.String() != line {
+		t.Fatalf("expected %q, got: %q", line, e.String())
+	}
+
+	for _, bad := range []string{"", "vcan0 123#00", "(abc) vcan0 123#00", "(1.5) vcan0 123"} {
+		if _, err := ParseLogEntry(bad); err == nil {
+			t.Fatalf("ParseLogEntry(%q), expected error", bad)
+		}
+	}
+}
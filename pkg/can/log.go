@@ -0,0 +1,68 @@
+package can
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry is a single line of a `candump -L` log, e.g. "(1634567890.123456) vcan0 02000100#0064"
+type LogEntry struct {
+	Time  time.Time
+	Iface string
+	Frame Frame
+}
+
+// String returns the entry in the `candump -L` format, which can be replayed with canplayer
+func (e LogEntry) String() string {
+	return fmt.Sprintf("(%d.%06d) %s %s", e.Time.Unix(), e.Time.Nanosecond()/1000, e.Iface, e.Frame)
+}
+
+// ParseLogEntry parses a single line of a `candump -L` log
+func ParseLogEntry(line string) (LogEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return LogEntry{}, fmt.Errorf("invalid log line: expected '(<time>) <iface> <frame>'")
+	}
+	ts := strings.TrimSuffix(strings.TrimPrefix(fields[0], "("), ")")
+	secs, frac := ts, "0"
+	if i := strings.Index(ts, "."); i >= 0 {
+		secs, frac = ts[:i], ts[i+1:]
+	}
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return LogEntry{}, fmt.Errorf("invalid log line: bad timestamp %q", fields[0])
+	}
+	// pad or cut the fraction to nanoseconds
+	frac = (frac + "000000000")[:9]
+	nsec, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return LogEntry{}, fmt.Errorf("invalid log line: bad timestamp %q", fields[0])
+	}
+	f, err := FromLog(fields[2])
+	if err != nil {
+		return LogEntry{}, err
+	}
+	return LogEntry{Time: time.Unix(sec, nsec), Iface: fields[1], Frame: *f}, nil
+}
+
+// ReadLog reads every entry of a `candump -L` log, skipping blank lines
+func ReadLog(r io.Reader) ([]LogEntry, error) {
+	var entries []LogEntry
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		e, err := ParseLogEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -1,61 +1,87 @@
 package demo
 
 import (
-	"encoding/hex"
-	"fmt"
-	"log"
+	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/team23asu/pican/pkg/avoid"
 	"github.com/team23asu/pican/pkg/can"
 	"github.com/team23asu/pican/pkg/rnet"
+	"github.com/team23asu/pican/pkg/session"
 )
 
 // this code is meant to demonstrate the behavior of our proposed collision-detection system
 
 type Avoider struct {
-	disabled   bool
-	jsmRead    <-chan *can.Frame
-	chairSend  chan<- *can.Frame
-	sensors    []*Sensor
-	bearingDeg float64
+	jsmRead   <-chan *can.Frame
+	chairSend chan<- *can.Frame
+	sensors   []*Sensor
+	chair     avoid.State
+	measured  time.Time       // when the sensors last measured
+	governor  *avoid.Governor // caps the strategy's speed when governed
+
+	// the game loop changes these while frames are modified on another goroutine
+	mu       sync.Mutex
+	disabled bool
+	strategy avoid.Strategy
+	governed bool
+	recorder *session.Recorder // optional, see SetRecorder
+	last     avoid.Command
 }
 
 func NewCollisionAvoider(jsmRead, chairSend chan *can.Frame) *Avoider {
-	thresholdMeters := 25.0
-	frontPushback := 0.5
-	sidePushback := 2.0
+	thresholdMeters := 1.0
 
+	strategy := avoid.NewClosestPushback()
 	return &Avoider{
 		jsmRead:   jsmRead,
 		chairSend: chairSend,
 		sensors: []*Sensor{
-			NewSensor(SENSOR_FRONT_CENTER, thresholdMeters, frontPushback),
-			NewSensor(SENSOR_FRONT_LEFT, thresholdMeters, sidePushback),
-			NewSensor(SENSOR_FRONT_RIGHT, thresholdMeters, sidePushback),
+			NewSensor(SENSOR_FRONT_CENTER, thresholdMeters),
+			NewSensor(SENSOR_FRONT_LEFT, thresholdMeters),
+			NewSensor(SENSOR_FRONT_RIGHT, thresholdMeters),
 		},
+		// the pushback constants live in the strategy
+		strategy: strategy,
+		governor: avoid.NewGovernor(strategy),
 	}
 }
 
-func (a *Avoider) Draw(screen *ebiten.Image) {
-	if a.disabled {
+func (a *Avoider) Draw(screen *ebiten.Image, cam Camera) {
+	if a.IsDisabled() {
 		return
 	}
 	for _, s := range a.sensors {
-		s.Draw(screen)
+		s.Draw(screen, cam)
 	}
 }
 
-func (a *Avoider) Update(chairPosition Vector2D, objects []*Object, chairBearingDeg float64) error {
-	a.bearingDeg = chairBearingDeg
+func (a *Avoider) Update(pose Pose, world Raycaster, chair avoid.State) error {
+	a.chair = chair
+	a.measured = time.Now()
 
+	a.mu.Lock()
+	rec := a.recorder
+	a.mu.Unlock()
 	for _, s := range a.sensors {
-		s.MeasureDistance(chairPosition, objects)
+		s.MeasureDistance(pose, world)
+		if rec != nil {
+			r := s.Reading()
+			rec.Sensor(session.Sensor{Name: r.Name, AimDeg: r.AimDeg, MountX: r.Mount.X, MountY: r.Mount.Y, Meters: r.Meters, Threshold: r.Threshold})
+		}
 	}
-	// asynchronously modify the movement frame
+	// asynchronously modify the movement frame, with what the sensors measured just now
+	in := a.input()
 	go func() {
 		select {
 		case f := <-a.jsmRead:
-			a.chairSend <- a.modifyFrame(f)
+			out := a.modifyFrame(f, in)
+			if rec != nil {
+				rec.Frame(rnet.SIDE_JSM, "", f)
+				rec.Frame(rnet.SIDE_CHAIR, "", out)
+			}
+			a.chairSend <- out
 		// note: bidirectional communication is required in the actual device but is not necessary in our demo,
 		//       so we do not bother wiring up jsmSend and chairRead channels.
 		default:
@@ -65,50 +91,117 @@ func (a *Avoider) Update(chairPosition Vector2D, objects []*Object, chairBearing
 	return nil
 }
 
-func (a *Avoider) modifyFrame(f *can.Frame) *can.Frame {
+func (a *Avoider) modifyFrame(f *can.Frame, in avoid.Input) *can.Frame {
+	a.mu.Lock()
+	disabled, strategy, rec := a.disabled, a.strategy, a.recorder
+	s := strategy
+	if a.governed {
+		s = a.governor
+	}
+	a.mu.Unlock()
 	// don't modify non-movement frames
-	if !rnet.IsMovementFrame(f.ID) || a.disabled {
+	if !rnet.IsMovementFrame(f.ID) || disabled {
 		return f
 	}
-	// now basically, the goal here is
-	// for each sensor, if the input is aligned with the sensor
-	// and it shows a reading less than the threshold distance
-	// then attenuate the input in that direction
-	fwd, side := rnet.ConvertDataToJoy(f.Data[0], f.Data[1])
-	inputVect := Vector2D{X: side, Y: fwd}
-	// hasChanged := false
-	var closest *Sensor
-	for _, s := range a.sensors {
-		if s.Triggered() {
-			if closest == nil || closest.observedMeters > s.observedMeters {
-				closest = s
-			}
-			// inputVect = inputVect.Add(s.Pushback(a.bearingDeg))
-			// hasChanged = true
-		}
+	out, cmd := avoid.Apply(s, in, f)
+	a.mu.Lock()
+	a.last = cmd
+	a.mu.Unlock()
+	if rec != nil {
+		rec.Decision(session.Decision{
+			Input:    session.Vector{X: cmd.Intent.X, Y: cmd.Intent.Y},
+			Pushback: session.Vector{X: cmd.Pushback.X, Y: cmd.Pushback.Y},
+			Output:   session.Vector{X: cmd.Vector.X, Y: cmd.Vector.Y},
+			Reason:   strategy.Name() + ": " + cmd.Explanation,
+		})
 	}
-	if closest == nil {
-		return f
+	return out
+}
+
+// input is what the strategy gets to decide on, other than the joystick
+func (a *Avoider) input() avoid.Input {
+	in := avoid.Input{Chair: a.chair, Time: a.measured}
+	for _, s := range a.sensors {
+		in.Readings = append(in.Readings, s.Reading())
 	}
-	inputVect = inputVect.Add(closest.Pushback(a.bearingDeg))
+	return in
+}
 
-	// avoid jerk by normalizing to the desired magnitude of the user's input
-	inputVect = inputVect.Normalize().Mul(Vector2D{X: side, Y: fwd}.Mag())
+// the methods below let a recorded session be replayed through the avoider, see pkg/replay
 
-	x, y := rnet.ConvertJoyToData(float32(inputVect.X), float32(inputVect.Y))
-	xxyy := hex.EncodeToString([]byte{uint8(x), uint8(y)})
-	line := fmt.Sprintf("02000%s00#%s", JSM_ID, xxyy)
-	fn, err := can.FromLog(line)
-	if err != nil {
-		log.Printf("error re-building frame: %v", err)
+// ObserveSensor sets the reading of the sensor with the same name, as if it had just measured it
+func (a *Avoider) ObserveSensor(r session.Sensor) {
+	for _, s := range a.sensors {
+		if s.location.String() == r.Name {
+			s.observedMeters = r.Meters
+		}
 	}
-	return fn // modified f
+}
+
+// ObserveChair sets the state of the chair the strategy decides for
+func (a *Avoider) ObserveChair(c session.Chair) {
+	a.chair = avoid.State{BearingDeg: c.BearingDeg, Speed: c.Speed, Velocity: c.Velocity, TurnRate: c.TurnRate}
+}
+
+// ObserveTime sets when the sensors measured, for the governor's range rates
+func (a *Avoider) ObserveTime(t time.Time) {
+	a.measured = t
+}
+
+// ModifyFrame is the frame the avoider sends to the chair for a frame from the JSM
+func (a *Avoider) ModifyFrame(f *can.Frame) *can.Frame {
+	return a.modifyFrame(f, a.input())
+}
+
+// SetStrategy changes how the avoider decides what to send to the chair
+func (a *Avoider) SetStrategy(s avoid.Strategy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.strategy = s
+	a.governor.SetStrategy(s)
+}
+
+func (a *Avoider) Strategy() avoid.Strategy {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.strategy
+}
+
+// SetGoverned caps the strategy's forward speed to what the chair can stop from in time, see avoid.Governor
+func (a *Avoider) SetGoverned(governed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.governed = governed
+}
+
+func (a *Avoider) IsGoverned() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.governed
+}
+
+// LastCommand returns the most recent decision, for showing on screen
+func (a *Avoider) LastCommand() avoid.Command {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last
+}
+
+// SetRecorder records sensor readings, frames and decisions to a session, nil stops recording
+func (a *Avoider) SetRecorder(r *session.Recorder) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.recorder = r
 }
 
 func (a *Avoider) IsDisabled() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.disabled
 }
 
 func (a *Avoider) SetDisabled(disabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.disabled = disabled
 }
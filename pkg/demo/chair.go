@@ -4,7 +4,9 @@ import (
 	"image/color"
 	"math"
 
+	"github.com/team23asu/pican/pkg/avoid"
 	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/poly"
 	"github.com/team23asu/pican/pkg/rnet"
 	"golang.org/x/image/colornames"
 
@@ -12,31 +14,21 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
-func toDegrees(rads float64) float64 {
-	return rads * 180.0 / math.Pi
-}
-
-func toRads(degrees float64) float64 {
-	return math.Pi * degrees / 180.0
-}
-
 const (
-	// update interval is always 60 Hz or 1/60 seconds
-	INPUT_SCALE_SIDE = -1.0
-	INPUT_SCALE_FWD  = 1.0
+	// ebiten calls Update 60 times a second
+	TICK_SECONDS = 1.0 / 60.0
 
 	// METERS_PER_MILE = 1609.34
 	// HOURS_PER_SECOND = 1.0 / 60.0 / 60.0
 	METERS_PER_INCH  = 0.0254
-	MILES_PER_HOUR   = 0.44704 // 1 mph, in meters per second
-	PIXELS_PER_METER = 25      // arbitrary
+	MILES_PER_HOUR   = avoid.MILES_PER_HOUR
+	PIXELS_PER_METER = 25 // arbitrary, only used for drawing
 
-	// page 37, Permobil M300 Corpus HD User Manual:
-	// https://permobilwebcdn.azureedge.net/media/yeua1ubg/m300_corpus_hd-user_manual-eng-us-334794.pdf
-	CHAIR_WIDTH_METERS           = 0.860
-	CHAIR_LENGTH_METERS          = 1.190
-	CHAIR_MIN_TURN_RADIUS_METERS = 0.560
-	CHAIR_WEIGHT_POUNDS          = 450.0
+	// the chair's dimensions live with the avoidance strategies, which need them too
+	CHAIR_WIDTH_METERS           = avoid.CHAIR_WIDTH_METERS
+	CHAIR_LENGTH_METERS          = avoid.CHAIR_LENGTH_METERS
+	CHAIR_MIN_TURN_RADIUS_METERS = avoid.CHAIR_MIN_TURN_RADIUS_METERS
+	CHAIR_WEIGHT_POUNDS          = avoid.CHAIR_WEIGHT_POUNDS
 
 	CHAIR_SPEED_DAMPING   = 0.7   // arbitrary
 	CHAIR_SPEED           = 160.0 // no idea
@@ -45,21 +37,8 @@ const (
 )
 
 var (
-	CHAIR_INDOOR_SPEEDS_M_S = []float64{
-		0.8 * MILES_PER_HOUR, // 0.3576 m/s
-		1.3 * MILES_PER_HOUR,
-		1.7 * MILES_PER_HOUR,
-		2.2 * MILES_PER_HOUR,
-		2.7 * MILES_PER_HOUR,
-	}
-
-	CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S = []float64{
-		0.319,
-		0.429,
-		0.565,
-		0.693,
-		0.782,
-	}
+	CHAIR_INDOOR_SPEEDS_M_S           = avoid.CHAIR_INDOOR_SPEEDS_M_S
+	CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S = avoid.CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S
 
 	CHAIR_MAX_INDOOR_ANGULAR_SPEEDS_RAD_S = []float64{
 		CHAIR_INDOOR_SPEEDS_M_S[0] / CHAIR_MIN_TURN_RADIUS_METERS,
@@ -73,15 +52,23 @@ var (
 	CHAIR_LENGTH_PIXELS = CHAIR_LENGTH_METERS * PIXELS_PER_METER
 )
 
+// chairOutline is the chair in its own body frame
+var chairOutline = poly.MustNew(
+	poly.Point{X: -CHAIR_WIDTH_METERS / 2, Y: CHAIR_LENGTH_METERS / 2},
+	poly.Point{X: CHAIR_WIDTH_METERS / 2, Y: CHAIR_LENGTH_METERS / 2},
+	poly.Point{X: CHAIR_WIDTH_METERS / 2, Y: -CHAIR_LENGTH_METERS / 2},
+	poly.Point{X: -CHAIR_WIDTH_METERS / 2, Y: -CHAIR_LENGTH_METERS / 2},
+)
+
 type Chair struct {
 	// speedMode    int // 0, 1. 0=indoor, 1=normal
 	speedSetting int // 0 through 4
 
-	// chair position
+	// chair position in the world, in meters, and compass bearing. see frame.go
 	position   Vector2D
 	bearingDeg float64
 
-	// chair joystick input
+	// chair joystick input, forward is positive, and side is positive to the right
 	joySide, joyForward float64
 
 	// chair momentum
@@ -99,20 +86,47 @@ func NewChair(x, y float64, bus chan *can.Frame) *Chair {
 	return &Chair{
 		speedSetting: 0,
 		bearingDeg:   0.0,
-		position:     Vector2D{x, y},
+		position:     Vector2D{X: x, Y: y},
 		bus:          bus,
 		img:          img,
 	}
 }
 
 func (c *Chair) SetPosition(x, y float64) {
-	c.position = Vector2D{x, y}
+	c.position = Vector2D{X: x, Y: y}
 }
 
 func (c *Chair) SetBearing(degrees float64) {
 	c.bearingDeg = degrees
 }
 
+// Pose returns where the chair is and which way it faces
+func (c *Chair) Pose() Pose {
+	return Pose{Position: c.position, BearingDeg: c.bearingDeg}
+}
+
+// Footprint returns the outline of the chair in the world
+func (c *Chair) Footprint() *poly.Polygon {
+	return chairOutline.At(c.Pose().Poly())
+}
+
+// SpeedSetting returns the current speed level, 0 through 4
+func (c *Chair) SpeedSetting() int {
+	return c.speedSetting
+}
+
+// State is what the chair is doing, for the avoidance strategies
+func (c *Chair) State() avoid.State {
+	v := c.joyForward * CHAIR_INDOOR_SPEEDS_M_S[c.speedSetting]
+	return avoid.State{
+		BearingDeg: c.bearingDeg,
+		Speed:      c.speedSetting,
+		Velocity:   v,
+		// the same turn the chair makes in Update
+		TurnRate: avoid.TurnRate(c.joySide, v, c.speedSetting),
+	}
+}
+
 func (c *Chair) SetSpeed(s int) {
 	c.speedSetting = s % len(CHAIR_INDOOR_SPEEDS_M_S)
 }
@@ -131,52 +145,40 @@ func (c *Chair) Update() error {
 	case f := <-c.bus:
 		if rnet.IsMovementFrame(f.ID) {
 			c.joyForward, c.joySide = rnet.ConvertDataToJoy(f.Data[0], f.Data[1])
+		} else if ctl, ok := rnet.DecodeControl(f); ok && ctl.Kind == rnet.CONTROL_SPEED {
+			// follow the speed setting sent by the JSM
+			c.SetSpeed(rnet.SpeedLevel(ctl.Speed))
 		}
 	default:
 		//
 	}
 
-	fwdLen := c.joyForward * CHAIR_INDOOR_SPEEDS_M_S[c.speedSetting]
-	// recall: arc length = radius*theta, so MAX{ theta } = length / MIN { radius }
-	// so we restrict the max turning angle to whichever would produce an arc of the forward motion's length
-	maxTurnRads := fwdLen / CHAIR_MIN_TURN_RADIUS_METERS
-	// desiredRads := CHAIR_INDOOR_ANGULAR_SPEEDS_RAD_S[c.speedSetting] * c.joySide
-	// turnRads := math.Min(maxTurnRads, math.Abs(desiredRads))
-	// turnRads := maxTurnRads
-	// c.bearingDeg += math.Copysign(toDegrees(turnRads), c.joySide)
-	c.bearingDeg += toDegrees(c.joySide * maxTurnRads)
+	// distance covered this tick
+	v := c.joyForward * CHAIR_INDOOR_SPEEDS_M_S[c.speedSetting]
+	fwdLen := v * TICK_SECONDS
+	// recall: arc length = radius*theta, so MAX{ theta } = length / MIN { radius }, and the speed setting caps the
+	// turn rate on top of that. avoid.TurnRate does both, so the avoidance strategies plan with the same turns.
+	// it counts counterclockwise, the bearing clockwise
+	c.bearingDeg -= toDegrees(avoid.TurnRate(c.joySide, v, c.speedSetting) * TICK_SECONDS)
 	if c.bearingDeg >= 360.0 {
 		c.bearingDeg -= 360.0
 	}
 	if c.bearingDeg < 0.0 {
 		c.bearingDeg += 360.0
 	}
-	bearing := Vector2D{-math.Sin(c.bearingDeg * math.Pi / 180.0), math.Cos(c.bearingDeg * math.Pi / 180.0)}.Normalize()
-
-	c.position = c.position.Add(bearing.Mul(fwdLen * PIXELS_PER_METER))
+	c.position = c.position.Add(c.Pose().Forward().Mul(fwdLen))
 
 	return nil
 }
 
-func (c *Chair) Draw(screen *ebiten.Image) {
-	sw, sh := screen.Size()
-	cw, ch := c.img.Size()
+func (c *Chair) Draw(screen *ebiten.Image, cam Camera) {
 	if c.img != nil {
-		// op := rotate(c.img, c.bearingDeg)
-		// op.GeoM.Translate(c.position.X, c.position.Y)
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(sw-cw)/2, float64((sh-ch)/2))
-		screen.DrawImage(c.img, op)
+		screen.DrawImage(c.img, cam.ImageOptions(c.img, c.Pose()))
 	}
 	// ebitenutil.DebugPrintAt(screen, fmt.Sprintf("pos: (%.1f, %.1f)", c.position.X, c.position.Y), (sw-cw)/2, ch+sh/2)
-	ebitenutil.DrawLine(
-		screen,
-		screenWidth/2,
-		screenHeight/2,
-		INPUT_SCALE_SIDE*JOY_LINE_LENGTH*c.joySide+screenWidth/2,
-		INPUT_SCALE_FWD*JOY_LINE_LENGTH*c.joyForward+screenHeight/2,
-		colornames.Yellow,
-	)
+	// the joystick, as the chair gets it. screen Y is down
+	x, y := cam.ToScreen(c.position)
+	ebitenutil.DrawLine(screen, x, y, x+JOY_LINE_LENGTH*c.joySide, y-JOY_LINE_LENGTH*c.joyForward, colornames.Yellow)
 }
 
 func generateChairImage(clr color.Color) *ebiten.Image {
@@ -191,19 +193,3 @@ func generateChairImage(clr color.Color) *ebiten.Image {
 
 	return img
 }
-
-func rotate(img *ebiten.Image, degrees float64) *ebiten.DrawImageOptions {
-	w, h := img.Size()
-	op := &ebiten.DrawImageOptions{}
-
-	// Move the image's center to the screen's upper-left corner.
-	// This is a preparation for rotating. When geometry matrices are applied,
-	// the origin point is the upper-left corner.
-	op.GeoM.Translate(-float64(w)/2, -float64(h)/2)
-
-	// Rotate the image. As a result, the anchor point of this rotate is
-	// the center of the image.
-	op.GeoM.Rotate(toRads(degrees)) // and subtract 90 because we draw facing up but 0 is facing right.
-
-	return op
-}
@@ -0,0 +1,93 @@
+package demo
+
+// coordinate frames. everything the simulation does is in SI units, only drawing converts to pixels.
+//
+//   - world: meters, X to the east (right on the map), Y to the north (up on the map).
+//   - chair body: meters, origin at the center of the chair, X to the chair's right and Y straight ahead.
+//     the avoidance strategies work in it. a reading is measured from where its sensor is mounted, given by
+//     avoid.Reading.Mount, and a sensor aimed at 90° looks down +Y.
+//   - screen: pixels, origin top left, Y down. only Camera knows about it.
+//
+// the chair's bearing is a compass bearing: degrees clockwise from north, 0 drives towards +Y in the world.
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/team23asu/pican/pkg/poly"
+)
+
+func toDegrees(rads float64) float64 {
+	return rads * 180.0 / math.Pi
+}
+
+func toRads(degrees float64) float64 {
+	return math.Pi * degrees / 180.0
+}
+
+// Pose is where the chair is in the world and which way it faces
+type Pose struct {
+	Position   Vector2D // world, meters
+	BearingDeg float64  // clockwise from north
+}
+
+// Forward is the direction the chair faces, in the world
+func (p Pose) Forward() Vector2D {
+	s, c := math.Sincos(toRads(p.BearingDeg))
+	return Vector2D{X: s, Y: c}
+}
+
+// Right is the direction to the chair's right, in the world
+func (p Pose) Right() Vector2D {
+	s, c := math.Sincos(toRads(p.BearingDeg))
+	return Vector2D{X: c, Y: -s}
+}
+
+// DirToWorld turns a direction in the chair's body frame into one in the world
+func (p Pose) DirToWorld(d Vector2D) Vector2D {
+	return p.Right().Mul(d.X).Add(p.Forward().Mul(d.Y))
+}
+
+// ToWorld turns a point in the chair's body frame into one in the world
+func (p Pose) ToWorld(b Vector2D) Vector2D {
+	return p.Position.Add(p.DirToWorld(b))
+}
+
+// ToBody turns a point in the world into one in the chair's body frame
+func (p Pose) ToBody(w Vector2D) Vector2D {
+	d := w.Sub(p.Position)
+	return Vector2D{X: d.Dot(p.Right()), Y: d.Dot(p.Forward())}
+}
+
+// Poly returns the pose for moving polygons drawn in the chair's body frame into the world
+func (p Pose) Poly() poly.Pose {
+	// the body's axes are the world's turned clockwise by the bearing
+	return poly.Pose{X: p.Position.X, Y: p.Position.Y, Angle: -toRads(p.BearingDeg)}
+}
+
+// Camera draws the world onto the screen, centered on a pose and turned so that pose faces up
+type Camera struct {
+	Center         Pose
+	PixelsPerMeter float64
+	Width, Height  int // of the screen, pixels
+}
+
+// ToScreen returns the pixel a point in the world lands on
+func (c Camera) ToScreen(w Vector2D) (x, y float64) {
+	b := c.Center.ToBody(w)
+	return float64(c.Width)/2 + b.X*c.PixelsPerMeter, float64(c.Height)/2 - b.Y*c.PixelsPerMeter
+}
+
+// ImageOptions places an image of something with the given pose on the screen. the image is in pixels at the
+// camera's scale, centered on the thing's position, with its front at the top.
+func (c Camera) ImageOptions(img *ebiten.Image, p Pose) *ebiten.DrawImageOptions {
+	w, h := img.Size()
+	op := &ebiten.DrawImageOptions{}
+	// rotate around the image's center, GeoM rotates around the origin
+	op.GeoM.Translate(-float64(w)/2, -float64(h)/2)
+	// both bearings are clockwise, and so is ebiten's rotation on a screen with Y down
+	op.GeoM.Rotate(toRads(p.BearingDeg - c.Center.BearingDeg))
+	x, y := c.ToScreen(p.Position)
+	op.GeoM.Translate(x, y)
+	return op
+}
@@ -33,6 +33,7 @@ type GamepadSet struct {
 	set            map[ebiten.GamepadID]struct{}
 	lx, ly, rx, ry float64
 	bus            chan<- *can.Frame // the gamepad emits movement frames onto this channel to be read elsewhere
+	controls       []*can.Frame      // control frames (speed, horn, ...) waiting for room on the bus
 
 	mostRecentFrame *can.Frame // just used for drawing on screen.
 }
@@ -85,6 +86,17 @@ func (g *GamepadSet) GetButton(b ebiten.StandardGamepadButton) bool {
 	return false
 }
 
+// SendControl queues a control message, like pressing one of the JSM's buttons.
+// it is sent instead of the next movement frame.
+func (g *GamepadSet) SendControl(c rnet.Control) error {
+	f, err := c.Frame()
+	if err != nil {
+		return err
+	}
+	g.controls = append(g.controls, f)
+	return nil
+}
+
 func (g *GamepadSet) Update() error {
 	g.lx, g.ly, g.rx, g.ry = 0, 0, 0, 0
 	ids := inpututil.AppendJustConnectedGamepadIDs([]ebiten.GamepadID{})
@@ -127,7 +139,9 @@ func (g *GamepadSet) Update() error {
 	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
 		g.rx = 0.25
 	}
-	x, y := rnet.ConvertJoyToData(float32(g.lx), float32(g.ly))
+	// the stick's Y is down like the screen's, R-Net's forward is positive. see rnet.ConvertDataToJoy for the side
+	side, fwd := g.lx, -g.ly
+	x, y := rnet.ConvertJoyToData(float32(rnet.INPUT_SCALE_SIDE*side), float32(rnet.INPUT_SCALE_FWD*fwd))
 	xxyy := hex.EncodeToString([]byte{uint8(x), uint8(y)})
 	// note: +100 = 0x64 and -100 = 0x9C (two's complement of 0x64)
 	line := fmt.Sprintf("02000%s00#%s", JSM_ID, xxyy)
@@ -135,6 +149,15 @@ func (g *GamepadSet) Update() error {
 	if err != nil {
 		log.Printf("error building frame: %v", err)
 	}
+	if len(g.controls) > 0 {
+		select {
+		case g.bus <- g.controls[0]:
+			g.controls = g.controls[1:]
+		default:
+			// try again next update
+		}
+		return nil
+	}
 	select {
 	case g.bus <- f:
 		g.mostRecentFrame = f
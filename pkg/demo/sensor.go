@@ -1,11 +1,11 @@
 package demo
 
 import (
-	"log"
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/team23asu/pican/pkg/avoid"
 	"github.com/team23asu/pican/pkg/poly"
 	"golang.org/x/image/colornames"
 )
@@ -18,67 +18,79 @@ const (
 	SENSOR_FRONT_RIGHT
 )
 
+func (l SensorLocation) String() string {
+	switch l {
+	case SENSOR_FRONT_LEFT:
+		return "front-left"
+	case SENSOR_FRONT_CENTER:
+		return "front-center"
+	case SENSOR_FRONT_RIGHT:
+		return "front-right"
+	default:
+		return "unknown"
+	}
+}
+
+// SENSOR_HEIGHT_METERS is how high off the floor the sensors are mounted. they look straight ahead, so anything
+// lower, like a bench or a planter, is under their beams
+const SENSOR_HEIGHT_METERS = 0.5
+
 type Sensor struct {
 	location        SensorLocation
 	thresholdMeters float64
 	observedMeters  float64
-	pushback        float64
+	pose            Pose // of the chair at the last measurement
 }
 
-func NewSensor(location SensorLocation, thresholdMeters, pushback float64) *Sensor {
+func NewSensor(location SensorLocation, thresholdMeters float64) *Sensor {
 	return &Sensor{
 		location:        location,
 		thresholdMeters: thresholdMeters,
 		observedMeters:  0,
-		pushback:        pushback,
 	}
 }
 
-func (s *Sensor) Draw(screen *ebiten.Image) {
-	pos := Vector2D{X: screenWidth / 2, Y: (screenHeight - CHAIR_LENGTH_PIXELS) / 2}
+func (s *Sensor) Draw(screen *ebiten.Image, cam Camera) {
 	// dist := 0.0
 	// if s.observedMeters < s.thresholdMeters {
 	// 	dist = s.observedMeters
 	// }
-	ebitenutil.DrawLine(screen, pos.X, pos.Y, math.Ceil(pos.X+s.observedMeters*math.Cos(s.AimRad())), math.Ceil(pos.Y-s.observedMeters*math.Sin(s.AimRad())), colornames.Greenyellow)
+	x1, y1 := cam.ToScreen(s.pose.ToWorld(s.Mount()))
+	x2, y2 := cam.ToScreen(s.pose.ToWorld(s.Mount().Add(s.Aim().Mul(s.observedMeters))))
+	ebitenutil.DrawLine(screen, x1, y1, x2, y2, colornames.Greenyellow)
+}
+
+// Mount is where the sensor sits on the chair, in the chair's body frame. they're all at the middle of the front
+func (s *Sensor) Mount() Vector2D {
+	return Vector2D{X: 0, Y: CHAIR_LENGTH_METERS / 2}
 }
 
-func (s *Sensor) MeasureDistance(position Vector2D, objects []*Object) {
+// Aim is the direction the sensor looks in, in the chair's body frame
+func (s *Sensor) Aim() Vector2D {
+	y, x := math.Sincos(s.AimRad())
+	return Vector2D{X: x, Y: y}
+}
+
+// Raycaster finds the closest thing a ray hits, World is one
+type Raycaster interface {
+	Raycast(from, dir Vector2D, maxMeters float64) (poly.Hit, bool)
+}
+
+// MeasureDistance looks for the closest object in front of the sensor, with the chair at pose
+func (s *Sensor) MeasureDistance(pose Pose, world Raycaster) {
+	s.pose = pose
 	// reset sensor
 	s.observedMeters = s.thresholdMeters
-	// fire a ray from position toward the direction of s.AimRad()
-	d := 0.1                                    // step value
-	y, x := math.Sincos(s.AimRad())             // direction
-	stepVector := Vector2D{X: d * x, Y: -d * y} // step vector
-	testPoint := position                       // initial position
-
-	for objDist := d; objDist < s.thresholdMeters; objDist += d {
-		for _, o := range objects {
-			if o.shape.Contains(poly.Point{X: testPoint.X, Y: testPoint.Y}) {
-				log.Printf("%d point hit: %.2f %.2f", s.location, testPoint.X, testPoint.Y)
-				if objDist < s.observedMeters {
-					// closest object we've seen, store its distance
-					s.observedMeters = objDist
-				}
-				// done with this object
-				continue
-			}
-		}
-		// move our test point in the direction of the sensor
-		testPoint = testPoint.Add(stepVector)
-	}
-	if s.observedMeters < s.thresholdMeters {
-		// log.Printf("sensor %d: direction: %.1f", s.location, s.AimDeg())
-		// log.Printf("sensor %d: sensor ray: (%v)", s.location, testPoint.Sub(position))
-		// log.Printf("sensor %d: observed: %.2f", s.location, s.observedMeters)
+	// fire a ray from the sensor toward the direction it aims at
+	hit, ok := world.Raycast(pose.ToWorld(s.Mount()), pose.DirToWorld(s.Aim()), s.thresholdMeters)
+	if ok && hit.Distance < s.thresholdMeters {
+		s.observedMeters = hit.Distance
 	}
 }
 
-// AimDeg returns the orientation in degrees of a single sensor, based on its location
+// AimDeg returns the orientation in degrees of a single sensor, based on its location.
+// it's counterclockwise from the chair's right in the chair's body frame, so 90 is straight ahead, see frame.go
 func (s *Sensor) AimDeg() float64 {
-	// this can be confusing due to tranformation to screen coordinates (up is negative Y)
-	// (and on the author's consistency when using trigonometry in the various functions.)
-	// this all needs to be cleaned up with a whiteboard session!!
 	switch s.location {
 	case SENSOR_FRONT_LEFT:
 		return 135.0
@@ -102,29 +114,7 @@ func (s *Sensor) Triggered() bool {
 	return false
 }
 
-// Pushback returns a vector pointing away from a detected obstacle, if any
-func (s *Sensor) Pushback(bearingDeg float64) Vector2D {
-	if !s.Triggered() {
-		return Vector2D{}
-	}
-
-	pushAng := -90.0 // default backward
-	switch s.location {
-	case SENSOR_FRONT_CENTER:
-		pushAng = -90.0 // back
-	case SENSOR_FRONT_LEFT:
-		pushAng = 0.0 // right
-	case SENSOR_FRONT_RIGHT:
-		pushAng = 180.0 // left
-	default:
-	}
-	pushAng += bearingDeg
-
-	// scaling factor, exponentially ramp with proximity:
-	scale := math.Pow(math.Min(1.0, math.Max(1.0-s.observedMeters/s.thresholdMeters, 0.0)), 1.0)
-	// scale := 1.0
-
-	return Vector2D{
-		X: math.Cos(toRads(pushAng)), Y: math.Sin(toRads(pushAng)),
-	}.Mul(s.pushback * scale)
+// Reading is what the sensor saw in its last measurement, for the avoidance strategy
+func (s *Sensor) Reading() avoid.Reading {
+	return avoid.Reading{Name: s.location.String(), AimDeg: s.AimDeg(), Mount: s.Mount(), Meters: s.observedMeters, Threshold: s.thresholdMeters}
 }
@@ -7,7 +7,9 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/team23asu/pican/pkg/floorplan"
 	"github.com/team23asu/pican/pkg/poly"
+	"github.com/team23asu/pican/pkg/session"
 	"golang.org/x/image/colornames"
 )
 
@@ -17,156 +19,283 @@ const (
 	screenHeight = 480
 )
 
+// CLEARANCE_MARGIN_METERS is how much room the chair should keep from objects, on top of its half width
+const CLEARANCE_MARGIN_METERS = 0.1
+
+// GRID_CELL_METERS is the size of the cells objects are looked up in, about the size of a wall section
+const GRID_CELL_METERS = 2.0
+
+// Object is something in the world the chair can run into, its shape is in meters in the world frame.
+// it can be in pieces, like the walls of a corridor, and have holes, like the walls around a room.
 type Object struct {
-	shape *poly.Polygon
-	image *ebiten.Image
-	imgop *ebiten.DrawImageOptions
-	angle float64
+	shape    poly.MultiShape
+	color    color.Color
+	height   float64         // meters, 0 for all the way up
+	inflated poly.MultiShape // grown by the chair's half width and the clearance margin
+	pieces   []*poly.Polygon // convex, for collisions
 }
 
 type World struct {
-	chair     *Chair
-	avoidance *Avoider
-	objects   []*Object
-	worldview *ebiten.Image
+	chair       *Chair
+	avoidance   *Avoider
+	objects     []*Object
+	grid        *poly.Grid // of the objects' shapes, in the same order
+	seen        *poly.Grid // of the shapes tall enough for the sensors to see
+	start       Pose
+	recorder    *session.Recorder
+	showMargins bool
 }
 
-func NewWorld(c *Chair, a *Avoider) *World {
-	return &World{
+// NewWorld loads the floor plan at path, see pkg/floorplan, and puts the chair at its start.
+// without a path it's the built-in course.
+func NewWorld(c *Chair, a *Avoider, path string) (*World, error) {
+	objects := generateObjects()
+	start := Pose{}
+	if path != "" {
+		plan, err := floorplan.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		objects = planObjects(plan)
+		start = Pose{Position: Vector2D{X: plan.Start.X, Y: plan.Start.Y}, BearingDeg: plan.Start.BearingDeg}
+		log.Printf("[world] loaded %d objects from %s (%s)", len(objects), path, plan.Name)
+	}
+	shapes := make([]poly.Solid, len(objects))
+	var seen []poly.Solid
+	for i, o := range objects {
+		o.inflated = o.shape.Offset(CHAIR_WIDTH_METERS/2+CLEARANCE_MARGIN_METERS, poly.JOIN_ROUND)
+		o.pieces = o.shape.Decompose()
+		shapes[i] = o.shape
+		if o.height == 0 || o.height >= SENSOR_HEIGHT_METERS {
+			seen = append(seen, o.shape)
+		}
+	}
+	w := &World{
 		chair:     c,
 		avoidance: a,
-		objects:   generateObjects(),
+		objects:   objects,
+		grid:      poly.NewGrid(GRID_CELL_METERS, shapes),
+		seen:      poly.NewGrid(GRID_CELL_METERS, seen),
+		start:     start,
 	}
+	w.ResetChair()
+	return w, nil
+}
+
+// ResetChair puts the chair back where it started
+func (w *World) ResetChair() {
+	w.chair.SetPosition(w.start.Position.X, w.start.Position.Y)
+	w.chair.SetBearing(w.start.BearingDeg)
 }
 
 func (w *World) Draw(screen *ebiten.Image) {
 	sw, sh := screen.Size()
-	// we intend to rotate the drawing according to the chair's rotation,
-	// so make it sqrt(2) times the dimension of the longest screen side,
-	// so that it's guaranteed to fill the screen at any angle of rotation.
-	// (also we store it as half the size because it reduces division later on.)
-	halfL := math.Ceil(math.Sqrt2 * math.Max(float64(sw)/2, float64(sh)/2))
-	w.worldview = ebiten.NewImage(int(2*halfL), int(2*halfL))
-	cx, cy := w.chair.position.X, w.chair.position.Y
-	for _, o := range w.objects {
-		// if !o.shape.BoundingBoxOverlaps(cx-halfL, cy-halfL, cx+halfL, cy+halfL) {
-		// 	// object off-screen, skip it
-		// 	log.Printf("cam box %.2f %.2f, %.2f %.2f", cx-halfL, cy-halfL, cx+halfL, cy+halfL)
-		// 	a, b, c, d := o.shape.BoundingBox()
-		// 	log.Printf("obj box %.2f %.2f, %.2f %.2f", a, b, c, d)
-		// 	continue
-		// }
-		if o.image != nil {
-			op := &ebiten.DrawImageOptions{}
-			ox, oy, _, _ := o.shape.BoundingBox()
-			// log.Printf("obj x, y: %.2f, %.2f", ox, oy)
-			op.GeoM.Translate(halfL+ox-cx, halfL+oy-cy)
-			w.worldview.DrawImage(o.image, op)
-		} else {
-			log.Printf("obj %v missing image or image options", o)
+	// follow the chair, with its front facing up
+	cam := Camera{Center: w.chair.Pose(), PixelsPerMeter: PIXELS_PER_METER, Width: sw, Height: sh}
+	// only what's on screen. the view turns with the chair, so take everything within a corner's distance of it
+	r := math.Hypot(float64(sw), float64(sh)) / 2 / PIXELS_PER_METER
+	for _, i := range w.near(w.chair.position, r) {
+		o := w.objects[i]
+		o.Draw(screen, cam)
+		if w.showMargins {
+			drawShape(screen, cam, o.inflated, colornames.Dimgray)
 		}
 	}
-	// DrawImageOptions for rotating the worldview
-	op := &ebiten.DrawImageOptions{}
+	w.chair.Draw(screen, cam)
 
-	// Move the worldview's center to the screen's upper-left corner.
-	// This is a preparation for rotating. When geometry matrices are applied,
-	// the origin point is the upper-left corner.
-	op.GeoM.Translate(-halfL, -halfL)
+	w.avoidance.Draw(screen, cam)
+}
 
-	// Rotate the image. As a result, the anchor point of this rotate is
-	// the center of the image.
-	op.GeoM.Rotate(toRads(-w.chair.bearingDeg))
+// TooClose is true if the chair's center is closer to an object than half its width plus the clearance margin.
+// the chair is longer than it is wide, so this is about its sides, not its front and back.
+func (w *World) TooClose() bool {
+	p := poly.Point{X: w.chair.position.X, Y: w.chair.position.Y}
+	for _, i := range w.near(w.chair.position, CHAIR_WIDTH_METERS/2+CLEARANCE_MARGIN_METERS) {
+		if w.objects[i].inflated.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Move the center of the rotated worldview to the middle of the screen
-	op.GeoM.Translate(screenWidth/2, screenHeight/2)
-	screen.DrawImage(w.worldview, op)
-	w.chair.Draw(screen)
+// SetShowMargins draws the clearance margins around objects
+func (w *World) SetShowMargins(show bool) {
+	w.showMargins = show
+}
 
-	w.avoidance.Draw(screen)
+func (w *World) ShowMargins() bool {
+	return w.showMargins
+}
+
+// SetRecorder records the chair, its sensors and the avoidance decisions to a session, nil stops recording
+func (w *World) SetRecorder(r *session.Recorder) {
+	w.recorder = r
+	w.avoidance.SetRecorder(r)
 }
 
 func (w *World) Update() error {
-	err := w.avoidance.Update(w.chair.position.Add(Vector2D{
-		X: CHAIR_WIDTH_PIXELS / 2.0, Y: 0, //-CHAIR_LENGTH_PIXELS / 2.0,
-	}), w.objects, w.chair.bearingDeg)
+	// recorded before the avoidance runs, so a replay sees the bearing the avoidance saw
+	if w.recorder != nil {
+		st := w.chair.State()
+		w.recorder.Chair(session.Chair{
+			X:          w.chair.position.X,
+			Y:          w.chair.position.Y,
+			BearingDeg: st.BearingDeg,
+			Speed:      st.Speed,
+			Velocity:   st.Velocity,
+			TurnRate:   st.TurnRate,
+		})
+	}
+
+	err := w.avoidance.Update(w.chair.Pose(), w, w.chair.State())
 	if err != nil {
 		log.Printf("collision avoidance error: %v", err)
 		return nil
 	}
 
-	lastPos := w.chair.position
+	lastPos, lastBearing := w.chair.position, w.chair.bearingDeg
 	err = w.chair.Update()
 	if err != nil {
 		log.Printf("chair update error: %v", err)
 		return nil
 	}
+	if !w.resolveCollisions() {
+		// wedged in somewhere pushing out doesn't fix, prevent floating through walls
+		w.chair.position, w.chair.bearingDeg = lastPos, lastBearing
+	}
+	return nil
+}
+
+// COLLISION_PASSES is how many times the chair is pushed out of objects in one update, for corners where
+// pushing out of one wall pushes it into another
+const COLLISION_PASSES = 4
 
-	for _, o := range w.objects {
-		// do a quick collision check
-		for _, p := range []poly.Point{
-			{X: w.chair.position.X + 1, Y: w.chair.position.Y + 1 - CHAIR_LENGTH_PIXELS/2},
-			{X: w.chair.position.X + CHAIR_WIDTH_PIXELS - 1, Y: w.chair.position.Y + 1 - CHAIR_LENGTH_PIXELS/2},
-			{X: w.chair.position.X + CHAIR_WIDTH_PIXELS - 1, Y: w.chair.position.Y - 1 + CHAIR_LENGTH_PIXELS/2},
-			{X: w.chair.position.X + 1, Y: w.chair.position.Y - 1 + CHAIR_LENGTH_PIXELS/2},
-		} {
-			if o.shape.Contains(p) {
-				// prevent floating through walls
-				w.chair.position = lastPos
+// resolveCollisions pushes the chair out of any object it drove into, along the shortest way out, so it slides
+// along walls rather than stopping dead. returns false if it's still stuck in something.
+func (w *World) resolveCollisions() bool {
+	for pass := 0; ; pass++ {
+		clear := true
+		footprint := w.chair.Footprint()
+		for _, i := range w.grid.Query(footprint.BoundingBox()) {
+			for _, piece := range w.objects[i].pieces {
+				c, hit := poly.Collide(footprint, piece)
+				if !hit {
+					continue
+				}
+				clear = false
+				if pass < COLLISION_PASSES {
+					w.chair.position = w.chair.position.Add(Vector2D{X: c.Normal.X, Y: c.Normal.Y}.Mul(c.Depth))
+					footprint = w.chair.Footprint()
+				}
 			}
 		}
-		if o.image == nil {
-			o.image = o.generateImage(colornames.Aliceblue)
+		if clear || pass == COLLISION_PASSES {
+			return clear
 		}
 	}
-	return nil
 }
 
-func (o *Object) generateImage(clr color.Color) *ebiten.Image {
-	x1, y1, x2, y2 := o.shape.BoundingBox()
+// Raycast returns where a ray from a point in the world first hits an object, within maxMeters.
+// the sensors look straight ahead, objects lower than they are mounted aren't hit.
+func (w *World) Raycast(from, dir Vector2D, maxMeters float64) (poly.Hit, bool) {
+	ray := poly.Ray{Origin: poly.Point{X: from.X, Y: from.Y}, Dir: poly.Point{X: dir.X, Y: dir.Y}}
+	_, h, ok := w.seen.Raycast(ray, maxMeters)
+	return h, ok
+}
 
-	w, h := math.Ceil(x2-x1), math.Ceil(y2-y1)
-	img := ebiten.NewImage(int(w), int(h))
-	// bounding box
-	ebitenutil.DrawLine(img, 0, 0, w, 0, clr)
-	ebitenutil.DrawLine(img, w, 0, w, h, clr)
-	ebitenutil.DrawLine(img, w, h, 0, h, clr)
-	ebitenutil.DrawLine(img, 0, h, 0, 0, clr)
+// near returns the objects whose bounding boxes come within r of a point
+func (w *World) near(p Vector2D, r float64) []int {
+	return w.grid.Query(p.X-r, p.Y-r, p.X+r, p.Y+r)
+}
 
-	// draw edges, relative to bounding box origin of (x1, y1)
-	for _, e := range o.shape.Edges() {
-		ebitenutil.DrawLine(img, e[0].X-x1, e[0].Y-y1, e[1].X-x1, e[1].Y-y1, colornames.Saddlebrown)
+// Draw draws the object's edges, converting to pixels with the camera
+func (o *Object) Draw(screen *ebiten.Image, cam Camera) {
+	clr := o.color
+	if clr == nil {
+		clr = colornames.Saddlebrown
 	}
+	drawShape(screen, cam, o.shape, clr)
+}
 
-	return img
+// drawShape draws the edges of a shape in the world, around the outside and around its holes
+func drawShape(screen *ebiten.Image, cam Camera, s poly.MultiShape, clr color.Color) {
+	for _, r := range s.Rings() {
+		for _, e := range r.Edges() {
+			x1, y1 := cam.ToScreen(Vector2D{X: e.A.X, Y: e.A.Y})
+			x2, y2 := cam.ToScreen(Vector2D{X: e.B.X, Y: e.B.Y})
+			ebitenutil.DrawLine(screen, x1, y1, x2, y2, clr)
+		}
+	}
 }
 
+// MATERIAL_COLORS are the colors objects from a floor plan are drawn in, unless it says otherwise
+var MATERIAL_COLORS = map[string]color.Color{
+	floorplan.MATERIAL_DRYWALL:  colornames.Saddlebrown,
+	floorplan.MATERIAL_CONCRETE: colornames.Gray,
+	floorplan.MATERIAL_GLASS:    colornames.Lightblue,
+	floorplan.MATERIAL_WOOD:     colornames.Burlywood,
+	floorplan.MATERIAL_METAL:    colornames.Silver,
+	floorplan.MATERIAL_FABRIC:   colornames.Indianred,
+}
+
+// planObjects turns the objects of a floor plan into objects in the world
+func planObjects(plan *floorplan.Plan) []*Object {
+	objs := make([]*Object, 0, len(plan.Objects))
+	for _, po := range plan.Objects {
+		o := &Object{shape: po.Shape, height: po.Height, color: MATERIAL_COLORS[po.Material]}
+		if po.Color != "" {
+			o.color = colornames.Map[po.Color]
+		}
+		objs = append(objs, o)
+	}
+	return objs
+}
+
+// generateObjects lays out the course, in meters. the chair starts at the origin facing north (+Y)
 func generateObjects() []*Object {
-	objs := []*Object{
-		box(100, 100, 100, 100),
+	return []*Object{
+		// the walls around everything
+		room(-10, -12, 22, 50, 0.3),
+		// off to the right, a bit behind the start
+		box(4, -8, 4, 4),
+		// straight ahead, 6m to 34m from the start
+		corridor(-2, 6, 2.5, 28, 1.0),
 	}
-	objs = append(objs,
-		corridor(-50, -850, 2.5*PIXELS_PER_METER, 700, 1.0*PIXELS_PER_METER)...,
+}
+
+// rect returns a w by h rectangle with its south-west corner at x, y
+func rect(x, y, w, h float64) *poly.Polygon {
+	return poly.MustNew(
+		poly.Point{X: x, Y: y},
+		poly.Point{X: x + w, Y: y},
+		poly.Point{X: x + w, Y: y + h},
+		poly.Point{X: x, Y: y + h},
 	)
-	return objs
 }
 
+// box returns a solid w by h rectangle with its south-west corner at x, y
 func box(x, y, w, h float64) *Object {
+	return &Object{shape: poly.MultiShape{poly.MustNewShape(rect(x, y, w, h))}}
+}
+
+// room returns the walls around a w by h room with its south-west corner at x, y, thickness thick
+func room(x, y, w, h, thickness float64) *Object {
 	return &Object{
-		shape: poly.New(
-			poly.Point{X: x, Y: y},
-			poly.Point{X: x + w, Y: y},
-			poly.Point{X: x + w, Y: y + h},
-			poly.Point{X: x, Y: y + h},
-		),
+		shape: poly.MultiShape{poly.MustNewShape(
+			rect(x, y, w, h),
+			rect(x+thickness, y+thickness, w-2*thickness, h-2*thickness),
+		)},
 	}
 }
 
-func corridor(x, y, space, l, thickness float64) []*Object {
-	return []*Object{
-		// left wall
-		box(x, y, thickness, l),
-		// right wall
-		box(x+space+thickness, y, thickness, l),
+// corridor returns the walls of a corridor running north, with space between them, starting at x, y
+func corridor(x, y, space, l, thickness float64) *Object {
+	return &Object{
+		shape: poly.MultiShape{
+			// left wall
+			poly.MustNewShape(rect(x, y, thickness, l)),
+			// right wall
+			poly.MustNewShape(rect(x+space+thickness, y, thickness, l)),
+		},
 	}
 }
@@ -0,0 +1,158 @@
+package floorplan
+
+// floor plans for the demo, so a test course can be a copy of the hallways the chair actually drives through
+// rather than whatever is hard coded in the demo.
+//
+// a floor plan is JSON, in meters with X to the east and Y to the north:
+//
+//	{
+//	  "name": "2nd floor lobby",
+//	  "start": {"x": 0, "y": 0, "bearing_deg": 0},
+//	  "objects": [
+//	    {"name": "west wall", "material": "drywall", "polygons": [{"outer": [[-2, 0], [-1.8, 0], [-1.8, 20], [-2, 20]]}]},
+//	    {"name": "planter", "material": "concrete", "height": 0.4, "color": "darkgreen",
+//	     "polygons": [{"outer": [[1, 5], [1.5, 5], [1.5, 5.5], [1, 5.5]]}]},
+//	    {"name": "lobby", "material": "drywall",
+//	     "polygons": [{"outer": [[-8, 20], [8, 20], [8, 30], [-8, 30]], "holes": [[[-7.8, 20.2], [7.8, 20.2], [7.8, 29.8], [-7.8, 29.8]]]}]}
+//	  ]
+//	}
+//
+// start is where the chair starts, and which way it faces, clockwise from north.
+// an object is one or more polygons, each an outer ring of points and optionally holes cut out of it. the points can
+// go either way around. a doorway isn't a hole, draw the wall around it.
+// height is how tall an object is, leave it out for walls and anything else reaching up past the sensors.
+// material is one of the MATERIAL_ constants, color is a CSS color name and defaults to the material's.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/team23asu/pican/pkg/poly"
+	"golang.org/x/image/colornames"
+)
+
+const (
+	MATERIAL_DRYWALL  = "drywall"
+	MATERIAL_CONCRETE = "concrete"
+	MATERIAL_GLASS    = "glass"
+	MATERIAL_WOOD     = "wood"
+	MATERIAL_METAL    = "metal"
+	MATERIAL_FABRIC   = "fabric"
+)
+
+var MATERIALS = []string{MATERIAL_DRYWALL, MATERIAL_CONCRETE, MATERIAL_GLASS, MATERIAL_WOOD, MATERIAL_METAL, MATERIAL_FABRIC}
+
+// Polygon is a ring of [x, y] points with holes in it, see the package comment
+type Polygon struct {
+	Outer [][2]float64   `json:"outer"`
+	Holes [][][2]float64 `json:"holes,omitempty"`
+}
+
+// Object is a single entry of a floor plan
+type Object struct {
+	Name     string    `json:"name"`
+	Material string    `json:"material,omitempty"`
+	Height   float64   `json:"height,omitempty"` // meters, 0 for all the way up
+	Color    string    `json:"color,omitempty"`
+	Polygons []Polygon `json:"polygons"`
+
+	Shape poly.MultiShape `json:"-"` // the polygons, made when the plan is parsed
+}
+
+// Start is where the chair starts
+type Start struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	BearingDeg float64 `json:"bearing_deg"`
+}
+
+// Plan is a parsed floor plan
+type Plan struct {
+	Name    string    `json:"name"`
+	Start   Start     `json:"start"`
+	Objects []*Object `json:"objects"`
+}
+
+// Load reads and parses a floor plan file
+func Load(path string) (*Plan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a floor plan, checking every object so that mistakes show up when it is loaded
+func Parse(r io.Reader) (*Plan, error) {
+	var p Plan
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid floor plan: %w", err)
+	}
+	for i, o := range p.Objects {
+		if o == nil {
+			return nil, fmt.Errorf("object %d: null", i+1)
+		}
+		if err := o.compile(); err != nil {
+			return nil, fmt.Errorf("object %d (%q): %w", i+1, o.Name, err)
+		}
+	}
+	return &p, nil
+}
+
+func (o *Object) compile() error {
+	if o.Material != "" && !known(o.Material) {
+		return fmt.Errorf("unknown material %q, expected one of %v", o.Material, MATERIALS)
+	}
+	if o.Height < 0 {
+		return fmt.Errorf("height must be 0 or more")
+	}
+	if _, ok := colornames.Map[o.Color]; o.Color != "" && !ok {
+		return fmt.Errorf("unknown color %q", o.Color)
+	}
+	if len(o.Polygons) == 0 {
+		return fmt.Errorf("needs at least one polygon")
+	}
+	o.Shape = nil
+	for i, pg := range o.Polygons {
+		outer, err := poly.New(points(pg.Outer)...)
+		if err != nil {
+			return fmt.Errorf("polygon %d: %w", i+1, err)
+		}
+		var holes []*poly.Polygon
+		for j, h := range pg.Holes {
+			hole, err := poly.New(points(h)...)
+			if err != nil {
+				return fmt.Errorf("polygon %d, hole %d: %w", i+1, j+1, err)
+			}
+			holes = append(holes, hole)
+		}
+		s, err := poly.NewShape(outer, holes...)
+		if err != nil {
+			return fmt.Errorf("polygon %d: %w", i+1, err)
+		}
+		o.Shape = append(o.Shape, s)
+	}
+	return nil
+}
+
+func points(ring [][2]float64) []poly.Point {
+	pts := make([]poly.Point, len(ring))
+	for i, pt := range ring {
+		pts[i] = poly.Point{X: pt[0], Y: pt[1]}
+	}
+	return pts
+}
+
+func known(material string) bool {
+	for _, m := range MATERIALS {
+		if m == material {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package floorplan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/team23asu/pican/pkg/poly"
+)
+
+func TestParse(t *testing.T) {
+	plan, err := Parse(strings.NewReader(`{
+		"name": "lobby",
+		"start": {"x": 1, "y": 2, "bearing_deg": 90},
+		"objects": [
+			{"name": "west wall", "material": "drywall", "polygons": [{"outer": [[-2, 0], [-1.8, 0], [-1.8, 20], [-2, 20]]}]},
+			{"name": "planter", "material": "concrete", "height": 0.4, "color": "darkgreen",
+			 "polygons": [{"outer": [[1, 5], [1.5, 5], [1.5, 5.5], [1, 5.5]]}, {"outer": [[3, 5], [3.5, 5], [3.5, 5.5]]}]},
+			{"name": "lobby", "polygons": [{"outer": [[-8, 20], [8, 20], [8, 30], [-8, 30]], "holes": [[[-7.8, 20.2], [7.8, 20.2], [7.8, 29.8], [-7.8, 29.8]]]}]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("failed to parse floor plan: %v", err)
+	}
+	if plan.Name != "lobby" || plan.Start != (Start{X: 1, Y: 2, BearingDeg: 90}) {
+		t.Fatalf("Parse(), expected: lobby starting at 1, 2 facing 90, got: %v %v", plan.Name, plan.Start)
+	}
+	if len(plan.Objects) != 3 {
+		t.Fatalf("Parse(), expected: 3 objects, got: %d", len(plan.Objects))
+	}
+
+	type test struct {
+		name     string
+		q        poly.Point
+		contains bool
+	}
+	tests := []test{
+		{name: "west wall", q: poly.Point{X: -1.9, Y: 10}, contains: true},
+		{name: "west wall", q: poly.Point{X: 0, Y: 10}},
+		{name: "planter", q: poly.Point{X: 3.1, Y: 5.1}, contains: true},
+		{name: "lobby", q: poly.Point{X: -7.9, Y: 25}, contains: true},
+		// the floor of the lobby, inside the hole
+		{name: "lobby", q: poly.Point{X: 0, Y: 25}},
+	}
+	for _, test := range tests {
+		var o *Object
+		for _, po := range plan.Objects {
+			if po.Name == test.name {
+				o = po
+			}
+		}
+		if got := o.Shape.Contains(test.q); got != test.contains {
+			t.Fatalf("%s: Contains(%v), expected: %t, got: %t", test.name, test.q, test.contains, got)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	square := `[[0, 0], [1, 0], [1, 1], [0, 1]]`
+	type test struct {
+		name string
+		file string
+		want string
+		err  error
+	}
+	tests := []test{
+		{name: "not json", file: `{`, want: "invalid floor plan"},
+		{name: "unknown field", file: `{"walls": []}`, want: "invalid floor plan"},
+		{name: "null object", file: `{"objects": [null]}`, want: "object 1: null"},
+		{name: "unknown material", file: `{"objects": [{"name": "a", "material": "cheese", "polygons": [{"outer": ` + square + `}]}]}`, want: `object 1 ("a"): unknown material`},
+		{name: "negative height", file: `{"objects": [{"name": "a", "height": -1, "polygons": [{"outer": ` + square + `}]}]}`, want: "height"},
+		{name: "unknown color", file: `{"objects": [{"name": "a", "color": "blurple", "polygons": [{"outer": ` + square + `}]}]}`, want: "unknown color"},
+		{name: "no polygons", file: `{"objects": [{"name": "a"}]}`, want: "at least one polygon"},
+		{name: "too few points", file: `{"objects": [{"name": "a", "polygons": [{"outer": [[0, 0], [1, 1]]}]}]}`, want: "polygon 1", err: poly.ErrTooFewPoints},
+		{name: "bowtie", file: `{"objects": [{"name": "ok", "polygons": [{"outer": ` + square + `}]}, {"name": "b", "polygons": [{"outer": ` + square + `}, {"outer": [[0, 0], [1, 1], [1, 0], [0, 1]]}]}]}`,
+			want: `object 2 ("b"): polygon 2`, err: poly.ErrSelfIntersecting},
+		{name: "bad hole", file: `{"objects": [{"name": "a", "polygons": [{"outer": ` + square + `, "holes": [[[0, 0], [1, 1]]]}]}]}`, want: "polygon 1, hole 1", err: poly.ErrTooFewPoints},
+		{name: "hole outside", file: `{"objects": [{"name": "a", "polygons": [{"outer": ` + square + `, "holes": [[[5, 5], [6, 5], [6, 6]]]}]}]}`, want: "polygon 1", err: poly.ErrHoleOutside},
+	}
+	for _, test := range tests {
+		_, err := Parse(strings.NewReader(test.file))
+		if err == nil || !strings.Contains(err.Error(), test.want) || test.err != nil && !errors.Is(err, test.err) {
+			t.Fatalf("%s: Parse(), expected: %q, got: %v", test.name, test.want, err)
+		}
+	}
+}
+
+// the example in the docs has to keep working
+func TestLoadExample(t *testing.T) {
+	plan, err := Load("../../docs/floorplan-hallway.json")
+	if err != nil {
+		t.Fatalf("failed to load the example floor plan: %v", err)
+	}
+	// the chair starts in the hallway, not in anything
+	start := poly.Point{X: plan.Start.X, Y: plan.Start.Y}
+	for _, o := range plan.Objects {
+		if o.Shape.Contains(start) {
+			t.Fatalf("Load(), expected: the start to be clear, got: in %q", o.Name)
+		}
+	}
+}
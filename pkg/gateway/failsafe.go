@@ -0,0 +1,284 @@
+package gateway
+
+// the processors are where the collision avoidance lives, and they are the part most likely to go wrong.
+// each direction runs its pipeline on a separate worker goroutine so that the gateway can give up on it:
+// if a processor panics, or takes longer than the latency budget, the gateway latches a fault and from then on
+// handles every frame according to its FailSafeMode, without the processors, until ResetFault is called.
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+// FailSafeMode is what the gateway does with frames once the processors have faulted
+type FailSafeMode int
+
+const (
+	// FAILSAFE_PASSTHROUGH forwards every frame untouched, as if the gateway wasn't there.
+	// the user keeps full control of the chair, without collision avoidance.
+	FAILSAFE_PASSTHROUGH FailSafeMode = iota
+	// FAILSAFE_STOP forwards every frame but replaces the joystick position in movement frames
+	// with the center position, so the chair comes to a controlled stop and stays there.
+	FAILSAFE_STOP
+)
+
+func (m FailSafeMode) String() string {
+	switch m {
+	case FAILSAFE_PASSTHROUGH:
+		return "passthrough"
+	case FAILSAFE_STOP:
+		return "stop"
+	default:
+		return fmt.Sprintf("failsafe %d", int(m))
+	}
+}
+
+// ParseFailSafeMode converts "passthrough" or "stop" to a FailSafeMode
+func ParseFailSafeMode(s string) (FailSafeMode, error) {
+	switch s {
+	case "passthrough":
+		return FAILSAFE_PASSTHROUGH, nil
+	case "stop":
+		return FAILSAFE_STOP, nil
+	}
+	return 0, fmt.Errorf("unknown fail-safe mode %q, expected passthrough or stop", s)
+}
+
+const (
+	DEFAULT_PROCESS_BUDGET = 3 * time.Millisecond
+)
+
+// Fault describes why the gateway went into fail-safe mode
+type Fault struct {
+	Time   time.Time
+	Dir    Direction
+	Frame  can.Frame // the frame being processed when the fault happened
+	Reason string
+}
+
+func (f Fault) String() string {
+	return fmt.Sprintf("%s %s %s: %s", f.Time.Format("15:04:05.000"), f.Dir, f.Frame, f.Reason)
+}
+
+type job struct {
+	frame  can.Frame
+	result chan result // buffered, so a worker that finishes after we gave up on it never blocks
+}
+
+type result struct {
+	frames  []*can.Frame // frames to forward: emitted ones first, then the processed frame unless dropped
+	emitted int
+	dropped bool
+	panic   string
+}
+
+// worker runs the processors of one direction on its own goroutine
+type worker struct {
+	processors []Processor
+	jobs       chan job
+	done       chan struct{}
+	stopped    sync.Once
+}
+
+func startWorker(processors []Processor, jobs chan job) *worker {
+	w := &worker{processors: processors, jobs: jobs, done: make(chan struct{})}
+	go w.run()
+	return w
+}
+
+func (w *worker) run() {
+	for {
+		// once stopped, leave the jobs to the worker that replaced it
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+		select {
+		case j := <-w.jobs:
+			j.result <- w.process(j.frame)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *worker) process(f can.Frame) (res result) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = result{panic: fmt.Sprintf("panic: %v\n%s", r, debug.Stack())}
+		}
+	}()
+	emit := func(extra *can.Frame) {
+		c := *extra
+		res.frames = append(res.frames, &c)
+		res.emitted++
+	}
+	for _, proc := range w.processors {
+		if proc.Process(&f, emit) == DROP {
+			res.dropped = true
+			return res
+		}
+	}
+	res.frames = append(res.frames, &f)
+	return res
+}
+
+// stop tells an idle worker to exit, a stalled one exits once its processor returns
+func (w *worker) stop() {
+	w.stopped.Do(func() { close(w.done) })
+}
+
+// supervisor holds the latched fault shared by both directions
+type supervisor struct {
+	mode    FailSafeMode
+	budget  time.Duration
+	onFault func(Fault)
+
+	mu    sync.Mutex
+	fault *Fault
+}
+
+// latch records the first fault, later ones are ignored until the fault is reset
+func (s *supervisor) latch(f Fault) {
+	s.mu.Lock()
+	first := s.fault == nil
+	if first {
+		s.fault = &f
+	}
+	s.mu.Unlock()
+	if first && s.onFault != nil {
+		s.onFault(f)
+	}
+}
+
+func (s *supervisor) faulted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fault != nil
+}
+
+// Fault returns the latched fault, if the gateway is in fail-safe mode
+func (g *Gateway) Fault() (Fault, bool) {
+	g.supervisor.mu.Lock()
+	defer g.supervisor.mu.Unlock()
+	if g.supervisor.fault == nil {
+		return Fault{}, false
+	}
+	return *g.supervisor.fault, true
+}
+
+// FailSafeMode returns the configured fail-safe mode
+func (g *Gateway) FailSafeMode() FailSafeMode {
+	return g.supervisor.mode
+}
+
+// ResetFault clears a latched fault and restarts the processors. A worker stuck in a stalled
+// processor is abandoned, so only reset once whatever caused the fault has been dealt with.
+func (g *Gateway) ResetFault() {
+	g.supervisor.mu.Lock()
+	defer g.supervisor.mu.Unlock()
+	if g.supervisor.fault == nil {
+		return
+	}
+	for _, p := range g.paths {
+		p.mu.Lock()
+		if p.worker != nil {
+			p.worker.stop()
+			p.worker = startWorker(p.processors, p.jobs)
+		}
+		p.mu.Unlock()
+	}
+	g.supervisor.fault = nil
+}
+
+// SetProcessors replaces the pipeline for one direction, e.g. after reloading a rules file.
+// Frames already being processed finish with the old pipeline, the ones waiting for it go to the new one.
+// A latched fault stays latched.
+func (g *Gateway) SetProcessors(dir Direction, processors []Processor) {
+	p := g.paths[dir]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.worker != nil {
+		p.worker.stop()
+		p.worker = nil
+	}
+	p.processors = processors
+	if len(processors) > 0 {
+		p.worker = startWorker(processors, p.jobs)
+	}
+}
+
+// supervise runs f through the pipeline on the path's worker, within the latency budget.
+// it returns the frames to forward, falling back to the fail-safe mode on a fault.
+func (g *Gateway) supervise(p *path, f *can.Frame) []*can.Frame {
+	p.mu.Lock()
+	w := p.worker
+	p.mu.Unlock()
+	if w == nil {
+		// no processors, nothing that can fault
+		return []*can.Frame{f}
+	}
+	if g.supervisor.faulted() {
+		return g.failSafe(p, f)
+	}
+
+	j := job{frame: *f, result: make(chan result, 1)}
+	deadline := time.NewTimer(g.supervisor.budget)
+	defer deadline.Stop()
+	for sent := false; !sent; {
+		select {
+		case p.jobs <- j:
+			sent = true
+		case <-w.done:
+			// the processors were replaced or the fault reset while the frame was waiting for the old worker,
+			// it goes to the new one. only a gateway shutting down leaves the old worker in place.
+			p.mu.Lock()
+			next := p.worker
+			p.mu.Unlock()
+			if next == nil {
+				return []*can.Frame{f}
+			}
+			if next == w {
+				return g.failSafe(p, f)
+			}
+			w = next
+		case <-deadline.C:
+			// still busy with an earlier frame
+			g.supervisor.latch(Fault{Time: time.Now(), Dir: p.dir, Frame: *f, Reason: fmt.Sprintf("processors busy for over %s", g.supervisor.budget)})
+			return g.failSafe(p, f)
+		}
+	}
+	select {
+	case res := <-j.result:
+		if res.panic != "" {
+			g.supervisor.latch(Fault{Time: time.Now(), Dir: p.dir, Frame: *f, Reason: res.panic})
+			return g.failSafe(p, f)
+		}
+		atomic.AddUint64(&p.metrics.Injected, uint64(res.emitted))
+		if res.dropped {
+			atomic.AddUint64(&p.metrics.Dropped, 1)
+		}
+		return res.frames
+	case <-deadline.C:
+		g.supervisor.latch(Fault{Time: time.Now(), Dir: p.dir, Frame: *f, Reason: fmt.Sprintf("processing took over %s", g.supervisor.budget)})
+		return g.failSafe(p, f)
+	}
+}
+
+// failSafe handles a frame without the processors, according to the fail-safe mode
+func (g *Gateway) failSafe(p *path, f *can.Frame) []*can.Frame {
+	atomic.AddUint64(&p.metrics.FailSafe, 1)
+	if g.supervisor.mode == FAILSAFE_STOP && p.dir == JSM_TO_CHAIR && rnet.IsMovementFrame(f.ID) && f.DLC >= 2 {
+		stop := *f
+		stop.Data[0], stop.Data[1] = 0, 0
+		return []*can.Frame{&stop}
+	}
+	return []*can.Frame{f}
+}
@@ -0,0 +1,298 @@
+package gateway
+
+// the gateway sits between the JSM and the rest of the chair, with each on its own CAN interface.
+// every frame read from one side goes through that direction's pipeline of processors
+// and, unless dropped, is queued to be sent out the other side.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+// Direction is the way a frame crosses the gateway
+type Direction int
+
+const (
+	JSM_TO_CHAIR Direction = iota
+	CHAIR_TO_JSM
+)
+
+func (d Direction) String() string {
+	if d == JSM_TO_CHAIR {
+		return "jsm->chair"
+	}
+	return "chair->jsm"
+}
+
+// From returns the side of the gateway frames travelling in this direction are read from
+func (d Direction) From() rnet.Side {
+	if d == JSM_TO_CHAIR {
+		return rnet.SIDE_JSM
+	}
+	return rnet.SIDE_CHAIR
+}
+
+const (
+	DEFAULT_QUEUE_SIZE  = 64
+	DEFAULT_MAX_LATENCY = 5 * time.Millisecond // half of the 10ms JSM period, a later frame will be along soon
+	READ_ERROR_BACKOFF  = 10 * time.Millisecond
+)
+
+type Config struct {
+	JSM   can.Interface // interface wired to the joystick module
+	Chair can.Interface // interface wired to the power module and everything else
+
+	ToChair []Processor // applied in order to frames going from the JSM to the chair
+	ToJSM   []Processor // applied in order to frames going from the chair to the JSM
+
+	QueueSize  int           // frames waiting to be sent in each direction, DEFAULT_QUEUE_SIZE if 0
+	MaxLatency time.Duration // frames waiting longer than this are dropped rather than sent late, DEFAULT_MAX_LATENCY if 0
+
+	FailSafe FailSafeMode  // what to do with frames once the processors fault, see failsafe.go
+	Budget   time.Duration // time the processors may spend on a frame before it counts as a fault, DEFAULT_PROCESS_BUDGET if 0. keep it below MaxLatency
+	OnFault  func(Fault)   // optional, called once when a fault is latched
+
+	Trace func(Trace) // optional, called after every frame sent. it must be quick, it holds up the next frame
+}
+
+// Metrics counts what happened to frames in one direction
+type Metrics struct {
+	Received    uint64 // frames read
+	Forwarded   uint64 // frames sent out the other side, including injected ones
+	Dropped     uint64 // frames dropped by a processor
+	Injected    uint64 // extra frames emitted by processors or Inject
+	QueueFull   uint64 // frames dropped because the send queue was full
+	Stale       uint64 // frames dropped because they waited longer than MaxLatency
+	FailSafe    uint64 // frames handled in fail-safe mode
+	ReadErrors  uint64
+	WriteErrors uint64
+}
+
+type queued struct {
+	frame   *can.Frame
+	source  uint32 // id of the frame read from the interface that this one came from
+	ingress time.Time
+}
+
+// Trace describes a frame that crossed the gateway, see Config.Trace
+type Trace struct {
+	Dir      Direction
+	Frame    can.Frame // the frame as it was sent, after processing
+	SourceID uint32    // id of the frame read from the interface it came from
+	Ingress  time.Time // when the source frame was read
+	Egress   time.Time // when this frame was sent
+}
+
+// Latency is the time the frame spent crossing the gateway
+func (t Trace) Latency() time.Duration {
+	return t.Egress.Sub(t.Ingress)
+}
+
+// path is one direction through the gateway
+type path struct {
+	metrics    Metrics // first, so the counters are 64-bit aligned for atomic access on 32-bit ARM
+	dir        Direction
+	src, dst   can.Interface
+	processors []Processor
+	queue      chan queued
+	jobs       chan job // shared by the path's workers, so a frame waiting for one is picked up by its replacement
+
+	mu     sync.Mutex
+	worker *worker // runs the processors, nil if there are none
+
+	latency latencies
+}
+
+type Gateway struct {
+	paths      [2]*path
+	maxLatency time.Duration
+	closers    []io.Closer
+	supervisor *supervisor
+	trace      func(Trace)
+}
+
+func New(cfg Config) *Gateway {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DEFAULT_QUEUE_SIZE
+	}
+	if cfg.MaxLatency <= 0 {
+		cfg.MaxLatency = DEFAULT_MAX_LATENCY
+	}
+	if cfg.Budget <= 0 {
+		cfg.Budget = DEFAULT_PROCESS_BUDGET
+	}
+	g := &Gateway{
+		maxLatency: cfg.MaxLatency,
+		trace:      cfg.Trace,
+		supervisor: &supervisor{mode: cfg.FailSafe, budget: cfg.Budget, onFault: cfg.OnFault},
+	}
+	g.paths[JSM_TO_CHAIR] = &path{
+		dir:        JSM_TO_CHAIR,
+		src:        cfg.JSM,
+		dst:        cfg.Chair,
+		processors: cfg.ToChair,
+		queue:      make(chan queued, cfg.QueueSize),
+	}
+	g.paths[CHAIR_TO_JSM] = &path{
+		dir:        CHAIR_TO_JSM,
+		src:        cfg.Chair,
+		dst:        cfg.JSM,
+		processors: cfg.ToJSM,
+		queue:      make(chan queued, cfg.QueueSize),
+	}
+	for _, p := range g.paths {
+		p.jobs = make(chan job)
+		if len(p.processors) > 0 {
+			p.worker = startWorker(p.processors, p.jobs)
+		}
+	}
+	for _, iface := range []can.Interface{cfg.JSM, cfg.Chair} {
+		if c, ok := iface.(io.Closer); ok {
+			g.closers = append(g.closers, c)
+		}
+	}
+	return g
+}
+
+// Run bridges the two interfaces until ctx is cancelled. On the way out it closes
+// both interfaces to unblock any pending reads, so they should implement io.Closer
+// (can.Socket and can.Node do), otherwise Run waits for one more frame from each side.
+func (g *Gateway) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, p := range g.paths {
+		wg.Add(2)
+		go func(p *path) {
+			defer wg.Done()
+			g.read(ctx, p)
+		}(p)
+		go func(p *path) {
+			defer wg.Done()
+			g.write(ctx, p)
+		}(p)
+	}
+	<-ctx.Done()
+	for _, p := range g.paths {
+		p.mu.Lock()
+		if p.worker != nil {
+			p.worker.stop()
+		}
+		p.mu.Unlock()
+	}
+	for _, c := range g.closers {
+		if err := c.Close(); err != nil {
+			log.Printf("[gateway] close error: %v", err)
+		}
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Inject queues a frame to be sent in the given direction, bypassing the processors
+func (g *Gateway) Inject(dir Direction, f *can.Frame) error {
+	p := g.paths[dir]
+	atomic.AddUint64(&p.metrics.Injected, 1)
+	if !p.enqueue(f, f.ID, time.Now()) {
+		return fmt.Errorf("%s queue full", dir)
+	}
+	return nil
+}
+
+// Metrics returns a snapshot of the counters for one direction
+func (g *Gateway) Metrics(dir Direction) Metrics {
+	m := &g.paths[dir].metrics
+	return Metrics{
+		Received:    atomic.LoadUint64(&m.Received),
+		Forwarded:   atomic.LoadUint64(&m.Forwarded),
+		Dropped:     atomic.LoadUint64(&m.Dropped),
+		Injected:    atomic.LoadUint64(&m.Injected),
+		QueueFull:   atomic.LoadUint64(&m.QueueFull),
+		Stale:       atomic.LoadUint64(&m.Stale),
+		FailSafe:    atomic.LoadUint64(&m.FailSafe),
+		ReadErrors:  atomic.LoadUint64(&m.ReadErrors),
+		WriteErrors: atomic.LoadUint64(&m.WriteErrors),
+	}
+}
+
+func (g *Gateway) read(ctx context.Context, p *path) {
+	for {
+		f, err := p.src.Read()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			atomic.AddUint64(&p.metrics.ReadErrors, 1)
+			if errors.Is(err, can.ErrClosed) {
+				log.Printf("[gateway] %s: interface closed", p.dir)
+				return
+			}
+			log.Printf("[gateway] %s read error: %v", p.dir, err)
+			// don't spin if the interface is gone for good
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(READ_ERROR_BACKOFF):
+			}
+			continue
+		}
+		if f == nil {
+			continue
+		}
+		ingress := time.Now()
+		atomic.AddUint64(&p.metrics.Received, 1)
+		g.process(p, f, ingress)
+	}
+}
+
+// process runs f through the pipeline and queues whatever comes out
+func (g *Gateway) process(p *path, f *can.Frame, ingress time.Time) {
+	source := f.ID
+	for _, out := range g.supervise(p, f) {
+		p.enqueue(out, source, ingress)
+	}
+}
+
+func (p *path) enqueue(f *can.Frame, source uint32, ingress time.Time) bool {
+	select {
+	case p.queue <- queued{frame: f, source: source, ingress: ingress}:
+		return true
+	default:
+		atomic.AddUint64(&p.metrics.QueueFull, 1)
+		return false
+	}
+}
+
+func (g *Gateway) write(ctx context.Context, p *path) {
+	for {
+		var q queued
+		select {
+		case <-ctx.Done():
+			return
+		case q = <-p.queue:
+		}
+		if time.Since(q.ingress) > g.maxLatency {
+			// the chair is better off with the next fresh frame than with an old one
+			atomic.AddUint64(&p.metrics.Stale, 1)
+			continue
+		}
+		if err := p.dst.Send(q.frame); err != nil {
+			atomic.AddUint64(&p.metrics.WriteErrors, 1)
+			log.Printf("[gateway] %s send error: %v", p.dir, err)
+			continue
+		}
+		egress := time.Now()
+		atomic.AddUint64(&p.metrics.Forwarded, 1)
+		p.latency.record(q.source, egress.Sub(q.ingress))
+		if g.trace != nil {
+			g.trace(Trace{Dir: p.dir, Frame: *q.frame, SourceID: q.source, Ingress: q.ingress, Egress: egress})
+		}
+	}
+}
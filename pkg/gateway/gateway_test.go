@@ -0,0 +1,276 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+// bench wires a gateway between two in-memory buses, with a node on each playing the JSM and the chair
+type bench struct {
+	jsm, chair *can.Node
+	gw         *Gateway
+	cancel     context.CancelFunc
+	done       chan error
+}
+
+func newBench(t *testing.T, cfg Config) *bench {
+	jsmBus, chairBus := can.NewBus(), can.NewBus()
+	b := &bench{jsm: jsmBus.Connect(), chair: chairBus.Connect(), done: make(chan error, 1)}
+	cfg.JSM, cfg.Chair = jsmBus.Connect(), chairBus.Connect()
+	b.gw = New(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	go func() { b.done <- b.gw.Run(ctx) }()
+	t.Cleanup(b.stop)
+	return b
+}
+
+func (b *bench) stop() {
+	b.cancel()
+	<-b.done
+	b.done <- nil // let stop be called more than once
+	b.jsm.Close()
+	b.chair.Close()
+}
+
+func send(t *testing.T, n *can.Node, line string) {
+	f, err := can.FromLog(line)
+	if err != nil {
+		t.Fatalf("FromLog(%q): %v", line, err)
+	}
+	if err := n.Send(f); err != nil {
+		t.Fatalf("failed to send %q: %v", line, err)
+	}
+}
+
+func expect(t *testing.T, n *can.Node, line string) {
+	got := make(chan *can.Frame, 1)
+	go func() {
+		f, _ := n.Read()
+		got <- f
+	}()
+	select {
+	case f := <-got:
+		if f == nil || f.String() != line {
+			t.Fatalf("expected %s, got: %v", line, f)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %s", line)
+	}
+}
+
+func TestGatewayPipeline(t *testing.T) {
+	b := newBench(t, Config{
+		ToChair: []Processor{
+			Drop(func(f *can.Frame) bool { return f.ID == 0x123 }),
+			RewriteMovement(func(x, y int8) (int8, int8) { return x / 2, y / 2 }),
+			ProcessorFunc(func(f *can.Frame, emit Emit) Verdict {
+				if f.ID == 0x456 {
+					emit(&can.Frame{ID: 0x789, DLC: 1, Data: [8]uint8{0x01}})
+				}
+				return PASS
+			}),
+		},
+	})
+
+	send(t, b.jsm, "123#00")
+	send(t, b.jsm, "02000100#0A64")
+	expect(t, b.chair, "02000100#0532")
+	send(t, b.jsm, "456#00")
+	expect(t, b.chair, "789#01")
+	expect(t, b.chair, "456#00")
+
+	// the other direction has no processors
+	send(t, b.chair, "00E#048C1CBC00000000")
+	expect(t, b.jsm, "00E#048C1CBC00000000")
+
+	if err := b.gw.Inject(CHAIR_TO_JSM, &can.Frame{ID: 0x0C040100 | can.CAN_EFF_FLAG}); err != nil {
+		t.Fatalf("failed to inject: %v", err)
+	}
+	expect(t, b.jsm, "0C040100#")
+
+	b.stop()
+	m := b.gw.Metrics(JSM_TO_CHAIR)
+	if m.Received != 3 || m.Dropped != 1 || m.Injected != 1 || m.Forwarded != 3 {
+		t.Fatalf("unexpected jsm->chair metrics: %+v", m)
+	}
+	m = b.gw.Metrics(CHAIR_TO_JSM)
+	if m.Received != 1 || m.Injected != 1 || m.Forwarded != 2 {
+		t.Fatalf("unexpected chair->jsm metrics: %+v", m)
+	}
+}
+
+// flaky fails every other read, to make sure errors never turn into forwarded frames
+type flaky struct {
+	can.Interface
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *flaky) Read() (*can.Frame, error) {
+	f.mu.Lock()
+	f.calls++
+	fail := f.calls%2 == 1
+	f.mu.Unlock()
+	if fail {
+		return nil, errors.New("bus error")
+	}
+	return f.Interface.Read()
+}
+
+func TestGatewayReadErrors(t *testing.T) {
+	jsmBus, chairBus := can.NewBus(), can.NewBus()
+	jsm, chair := jsmBus.Connect(), chairBus.Connect()
+	defer jsm.Close()
+	defer chair.Close()
+	gwChair := chairBus.Connect()
+	gw := New(Config{JSM: &flaky{Interface: jsmBus.Connect()}, Chair: gwChair})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- gw.Run(ctx) }()
+
+	send(t, jsm, "02000100#0000")
+	expect(t, chair, "02000100#0000")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("expected Run to return context.Canceled, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("gateway did not shut down")
+	}
+	m := gw.Metrics(JSM_TO_CHAIR)
+	if m.ReadErrors == 0 || m.Forwarded != 1 {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+	if err := gwChair.Send(&can.Frame{}); err != can.ErrClosed {
+		t.Fatalf("expected gateway to close its interfaces, got: %v", err)
+	}
+}
+
+func TestGatewayFailSafe(t *testing.T) {
+	type test struct {
+		name   string
+		mode   FailSafeMode
+		fault  func()
+		reason string
+		want   string // what the chair receives for 02000100#1464 after the fault
+	}
+
+	stall := make(chan struct{})
+	defer close(stall)
+	tests := []test{
+		{name: "panic", mode: FAILSAFE_PASSTHROUGH, fault: func() { panic("boom") }, reason: "panic: boom", want: "02000100#1464"},
+		{name: "stall", mode: FAILSAFE_STOP, fault: func() { <-stall }, reason: "processing took over", want: "02000100#0000"},
+	}
+
+	for _, test := range tests {
+		var mu sync.Mutex
+		faulty, faults := true, 0
+		b := newBench(t, Config{
+			FailSafe:   test.mode,
+			Budget:     20 * time.Millisecond,
+			MaxLatency: 100 * time.Millisecond,
+			OnFault: func(f Fault) {
+				mu.Lock()
+				faults++
+				mu.Unlock()
+			},
+			ToChair: []Processor{
+				RewriteMovement(func(x, y int8) (int8, int8) {
+					mu.Lock()
+					f := faulty
+					mu.Unlock()
+					if f && y == 100 {
+						test.fault()
+					}
+					return x / 2, y / 2
+				}),
+			},
+		})
+
+		send(t, b.jsm, "02000100#1432")
+		expect(t, b.chair, "02000100#0A19")
+		if _, ok := b.gw.Fault(); ok {
+			t.Fatalf("%s: unexpected fault before the processor failed", test.name)
+		}
+
+		send(t, b.jsm, "02000100#1464")
+		expect(t, b.chair, test.want)
+		f, ok := b.gw.Fault()
+		if !ok || !strings.Contains(f.Reason, test.reason) {
+			t.Fatalf("%s: expected latched fault %q, got: %+v (%t)", test.name, test.reason, f, ok)
+		}
+
+		// latched: even frames the processor would handle fine now go through the fail-safe
+		mu.Lock()
+		faulty = false
+		mu.Unlock()
+		send(t, b.jsm, "02000100#1464")
+		expect(t, b.chair, test.want)
+		send(t, b.jsm, "00E#048C1CBC00000000")
+		expect(t, b.chair, "00E#048C1CBC00000000")
+
+		b.gw.ResetFault()
+		send(t, b.jsm, "02000100#1464")
+		expect(t, b.chair, "02000100#0A32")
+		if _, ok := b.gw.Fault(); ok {
+			t.Fatalf("%s: expected fault to be cleared", test.name)
+		}
+
+		b.stop()
+		mu.Lock()
+		if faults != 1 {
+			t.Fatalf("%s: expected OnFault to be called once, got: %d", test.name, faults)
+		}
+		mu.Unlock()
+		if m := b.gw.Metrics(JSM_TO_CHAIR); m.FailSafe != 3 {
+			t.Fatalf("%s: expected 3 frames handled in fail-safe mode, got: %+v", test.name, m)
+		}
+	}
+}
+
+func TestGatewayReloadWhileForwarding(t *testing.T) {
+	halve := func() []Processor {
+		return []Processor{RewriteMovement(func(x, y int8) (int8, int8) { return x / 2, y / 2 })}
+	}
+	b := newBench(t, Config{FailSafe: FAILSAFE_STOP, Budget: 100 * time.Millisecond, MaxLatency: time.Second, ToChair: halve()})
+
+	// reload over and over while the frames go through, none of them should end up in the fail-safe
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				b.gw.SetProcessors(JSM_TO_CHAIR, halve())
+				runtime.Gosched()
+			}
+		}
+	}()
+	for i := 0; i < 2000; i++ {
+		send(t, b.jsm, "02000100#1464")
+		expect(t, b.chair, "02000100#0A32")
+	}
+	close(stop)
+	<-stopped
+
+	b.stop()
+	if _, ok := b.gw.Fault(); ok {
+		t.Fatalf("unexpected fault from reloading")
+	}
+	if m := b.gw.Metrics(JSM_TO_CHAIR); m.FailSafe != 0 || m.Forwarded != 2000 {
+		t.Fatalf("expected all 2000 frames processed, got: %+v", m)
+	}
+}
@@ -0,0 +1,131 @@
+package gateway
+
+// the time a frame spends inside the gateway, from the moment it is read from one interface
+// until it (or whatever the processors turned it into) has been sent out the other one.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	HISTOGRAM_MIN    = time.Microsecond // everything faster lands in the first bucket
+	HISTOGRAM_GROWTH = 1.2              // each bucket is 20% wider than the one before, so quantiles are within 20%
+	HISTOGRAM_SIZE   = 90               // 1µs * 1.2^90 is over 10 minutes, plenty
+)
+
+// bucketBounds holds the upper bound of each histogram bucket
+var bucketBounds = func() []time.Duration {
+	b := make([]time.Duration, HISTOGRAM_SIZE)
+	for i := range b {
+		b[i] = time.Duration(float64(HISTOGRAM_MIN) * math.Pow(HISTOGRAM_GROWTH, float64(i)))
+	}
+	return b
+}()
+
+// Histogram counts durations in exponentially growing buckets. It is not safe for concurrent use.
+type Histogram struct {
+	counts [HISTOGRAM_SIZE]uint64
+	count  uint64
+	max    time.Duration
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	i := sort.Search(HISTOGRAM_SIZE, func(i int) bool { return bucketBounds[i] >= d })
+	if i == HISTOGRAM_SIZE {
+		i = HISTOGRAM_SIZE - 1
+	}
+	h.counts[i]++
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Quantile returns an upper bound for the q-th quantile (0.0 to 1.0) of the recorded durations
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			if bucketBounds[i] > h.max {
+				return h.max
+			}
+			return bucketBounds[i]
+		}
+	}
+	return h.max
+}
+
+// Stats summarizes the histogram
+func (h *Histogram) Stats() LatencyStats {
+	return LatencyStats{
+		Count: h.count,
+		P50:   h.Quantile(0.50),
+		P99:   h.Quantile(0.99),
+		Max:   h.max,
+	}
+}
+
+type LatencyStats struct {
+	Count         uint64
+	P50, P99, Max time.Duration
+}
+
+func (s LatencyStats) String() string {
+	return fmt.Sprintf("n=%d p50=%s p99=%s max=%s", s.Count, s.P50, s.P99, s.Max)
+}
+
+// latencies holds the histograms for one direction, overall and per source frame id
+type latencies struct {
+	mu    sync.Mutex
+	all   Histogram
+	perID map[uint32]*Histogram
+}
+
+func (l *latencies) record(id uint32, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.all.Record(d)
+	if l.perID == nil {
+		l.perID = make(map[uint32]*Histogram)
+	}
+	h, ok := l.perID[id]
+	if !ok {
+		h = &Histogram{}
+		l.perID[id] = h
+	}
+	h.Record(d)
+}
+
+// Latency returns the time frames spent crossing the gateway in one direction
+func (g *Gateway) Latency(dir Direction) LatencyStats {
+	l := &g.paths[dir].latency
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.all.Stats()
+}
+
+// LatencyByID returns the time frames spent crossing the gateway in one direction,
+// by the id of the frame read from the interface. Frames emitted by processors count
+// towards the frame that was being processed, injected ones towards their own id.
+func (g *Gateway) LatencyByID(dir Direction) map[uint32]LatencyStats {
+	l := &g.paths[dir].latency
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := make(map[uint32]LatencyStats, len(l.perID))
+	for id, h := range l.perID {
+		stats[id] = h.Stats()
+	}
+	return stats
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+func TestHistogram(t *testing.T) {
+	h := &Histogram{}
+	if s := h.Stats(); s.Count != 0 || s.P99 != 0 {
+		t.Fatalf("expected empty stats, got: %s", s)
+	}
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	type test struct {
+		q    float64
+		want time.Duration
+	}
+	for _, test := range []test{{0.5, 500 * time.Microsecond}, {0.99, 990 * time.Microsecond}, {1.0, time.Millisecond}} {
+		got := h.Quantile(test.q)
+		// buckets are HISTOGRAM_GROWTH wide, and quantiles are reported as the upper bound of the bucket
+		if got < test.want || float64(got) > float64(test.want)*HISTOGRAM_GROWTH {
+			t.Fatalf("Quantile(%v), expected: %s (+%.0f%%), got: %s", test.q, test.want, 100*(HISTOGRAM_GROWTH-1), got)
+		}
+	}
+	if h.Stats().Max != time.Millisecond {
+		t.Fatalf("expected max 1ms, got: %s", h.Stats().Max)
+	}
+}
+
+// pushTraffic sends n movement frames from the JSM side, one every period, and waits for all of them on the chair side
+func pushTraffic(t *testing.T, jsm, chair can.Interface, n int, period time.Duration) {
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		for i := 0; i < n; i++ {
+			if _, err := chair.Read(); err != nil {
+				return
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		f := &can.Frame{ID: 0x02000100 | can.CAN_EFF_FLAG, DLC: 2, Data: [8]uint8{uint8(i), 0x64}}
+		if err := jsm.Send(f); err != nil {
+			t.Fatalf("failed to send frame %d: %v", i, err)
+		}
+		time.Sleep(period)
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for frames to cross the gateway")
+	}
+}
+
+// checkLatency fails the test if frames took longer than budget to cross the gateway
+func checkLatency(t *testing.T, gw *Gateway, n int, budget time.Duration) {
+	s := gw.Latency(JSM_TO_CHAIR)
+	t.Logf("jsm->chair latency: %s", s)
+	if s.Count != uint64(n) {
+		t.Fatalf("expected %d frames measured, got: %s", n, s)
+	}
+	if s.P99 > budget {
+		t.Fatalf("p99 latency %s over budget %s", s.P99, budget)
+	}
+	byID := gw.LatencyByID(JSM_TO_CHAIR)
+	if byID[0x02000100|can.CAN_EFF_FLAG].Count != uint64(n) {
+		t.Fatalf("expected %d frames measured for the movement id, got: %v", n, byID)
+	}
+}
+
+func TestGatewayLatency(t *testing.T) {
+	var traces []Trace
+	b := newBench(t, Config{
+		ToChair: []Processor{RewriteMovement(func(x, y int8) (int8, int8) { return x, y / 2 })},
+		Trace:   func(tr Trace) { traces = append(traces, tr) },
+	})
+	const n = 100
+	pushTraffic(t, b.jsm, b.chair, n, time.Millisecond)
+	b.stop()
+	// generous, this runs under -race on shared CI machines
+	checkLatency(t, b.gw, n, 50*time.Millisecond)
+	if len(traces) != n || traces[0].Frame.Data[1] != 0x32 || traces[0].Latency() <= 0 {
+		t.Fatalf("expected %d traces of the rewritten frames, got %d, first: %+v", n, len(traces), traces[0])
+	}
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+// Verdict is what a Processor decided to do with a frame
+type Verdict int
+
+const (
+	PASS Verdict = iota // hand the (possibly rewritten) frame to the next processor, or forward it
+	DROP                // discard the frame, later processors never see it
+)
+
+// Emit sends an extra frame in the same direction as the frame being processed, e.g. to inject a reply or a copy.
+// Injected frames skip the rest of the pipeline.
+type Emit func(f *can.Frame)
+
+// Processor inspects a frame crossing the gateway. It rewrites a frame by modifying f in place.
+type Processor interface {
+	Process(f *can.Frame, emit Emit) Verdict
+}
+
+// ProcessorFunc lets an ordinary function act as a Processor
+type ProcessorFunc func(f *can.Frame, emit Emit) Verdict
+
+func (p ProcessorFunc) Process(f *can.Frame, emit Emit) Verdict {
+	return p(f, emit)
+}
+
+// Drop discards every frame for which match returns true
+func Drop(match func(f *can.Frame) bool) Processor {
+	return ProcessorFunc(func(f *can.Frame, emit Emit) Verdict {
+		if match(f) {
+			return DROP
+		}
+		return PASS
+	})
+}
+
+// RewriteMovement lets fn replace the joystick position in every movement frame.
+// x and y are the raw payload values (-100 to 100), see rnet.ConvertJoyToData.
+func RewriteMovement(fn func(x, y int8) (int8, int8)) Processor {
+	return ProcessorFunc(func(f *can.Frame, emit Emit) Verdict {
+		if !rnet.IsMovementFrame(f.ID) || f.DLC < 2 {
+			return PASS
+		}
+		x, y := fn(int8(f.Data[0]), int8(f.Data[1]))
+		f.Data[0], f.Data[1] = uint8(x), uint8(y)
+		return PASS
+	})
+}
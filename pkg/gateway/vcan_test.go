@@ -0,0 +1,58 @@
+//go:build linux
+// +build linux
+
+package gateway
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+const (
+	// what we are willing to add between the JSM and the chair, a fraction of the 10ms JSM period
+	VCAN_LATENCY_BUDGET = 2 * time.Millisecond
+)
+
+// TestGatewayLatencyVCAN pushes traffic through vcan0 and vcan1, see docs/dev-pi-setup.md to create them.
+// It is skipped when they don't exist.
+func TestGatewayLatencyVCAN(t *testing.T) {
+	for _, iface := range []string{"vcan0", "vcan1"} {
+		if _, err := net.InterfaceByName(iface); err != nil {
+			t.Skipf("%s not available: %v", iface, err)
+		}
+	}
+	bind := func(iface string) *can.Socket {
+		s, err := can.NewSocketBoundTo(iface)
+		if err != nil {
+			t.Fatalf("failed to bind to %s: %v", iface, err)
+		}
+		return s
+	}
+	jsm, chair := bind("vcan0"), bind("vcan1")
+	defer jsm.Close()
+	defer chair.Close()
+
+	gw := New(Config{
+		JSM:     bind("vcan0"),
+		Chair:   bind("vcan1"),
+		ToChair: []Processor{RewriteMovement(func(x, y int8) (int8, int8) { return x, y / 2 })},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- gw.Run(ctx) }()
+
+	// a second of JSM traffic at the real rate
+	const n = 100
+	pushTraffic(t, jsm, chair, n, 10*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * can.READ_TIMEOUT):
+		t.Fatalf("gateway did not shut down")
+	}
+	checkLatency(t, gw, n, VCAN_LATENCY_BUDGET)
+}
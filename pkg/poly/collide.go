@@ -0,0 +1,144 @@
+package poly
+
+// collision between polygons with the separating axis theorem: two convex polygons don't overlap if and only if
+// there is a line, parallel to one of their edges, that they don't overlap on when projected onto its normal.
+// if they overlap on all of them, the axis with the smallest overlap says how far and which way to push them apart.
+// concave polygons are split into triangles first, see Decompose.
+
+import (
+	"math"
+)
+
+// Contact is how two polygons overlap
+type Contact struct {
+	Depth  float64 // how far they overlap
+	Normal Point   // unit length, moving the first polygon Depth along it separates them
+}
+
+// Intersect tests two convex polygons for overlap. polygons that only touch don't overlap.
+func Intersect(a, b *Polygon) (Contact, bool) {
+	if len(a.points) == 0 || len(b.points) == 0 || !a.BoundingBoxOverlaps(b.BoundingBox()) {
+		return Contact{}, false
+	}
+	best := Contact{Depth: math.Inf(1)}
+	for _, p := range []*Polygon{a, b} {
+		for _, e := range p.Edges() {
+			axis := Point{X: e.A.Y - e.B.Y, Y: e.B.X - e.A.X}
+			l := math.Hypot(axis.X, axis.Y)
+			if l == 0 {
+				continue
+			}
+			axis = Point{X: axis.X / l, Y: axis.Y / l}
+			amin, amax := project(a, axis)
+			bmin, bmax := project(b, axis)
+			overlap := math.Min(amax, bmax) - math.Max(amin, bmin)
+			if overlap <= EPSILON {
+				return Contact{}, false
+			}
+			if overlap < best.Depth {
+				best = Contact{Depth: overlap, Normal: axis}
+			}
+		}
+	}
+	if math.IsInf(best.Depth, 1) {
+		return Contact{}, false
+	}
+	// point the normal from b towards a
+	ca, cb := a.center(), b.center()
+	if dot(Point{X: ca.X - cb.X, Y: ca.Y - cb.Y}, best.Normal) < 0 {
+		best.Normal = Point{X: -best.Normal.X, Y: -best.Normal.Y}
+	}
+	return best, true
+}
+
+// Collide tests any two polygons for overlap, splitting concave ones into convex pieces.
+// the contact is the deepest one between any two pieces.
+func Collide(a, b *Polygon) (Contact, bool) {
+	if len(a.points) == 0 || len(b.points) == 0 || !a.BoundingBoxOverlaps(b.BoundingBox()) {
+		return Contact{}, false
+	}
+	var deepest Contact
+	found := false
+	for _, pa := range a.Decompose() {
+		for _, pb := range b.Decompose() {
+			c, ok := Intersect(pa, pb)
+			if ok && (!found || c.Depth > deepest.Depth) {
+				deepest, found = c, true
+			}
+		}
+	}
+	return deepest, found
+}
+
+// project returns the range a polygon covers along an axis
+func project(p *Polygon, axis Point) (min, max float64) {
+	min, max = math.Inf(1), math.Inf(-1)
+	for _, pt := range p.points {
+		d := dot(pt, axis)
+		min, max = math.Min(min, d), math.Max(max, d)
+	}
+	return min, max
+}
+
+// center returns the average of the polygon's points, which is inside it if it's convex
+func (p *Polygon) center() Point {
+	var c Point
+	for _, pt := range p.points {
+		c.X += pt.X
+		c.Y += pt.Y
+	}
+	n := float64(len(p.points))
+	return Point{X: c.X / n, Y: c.Y / n}
+}
+
+// Decompose splits a polygon into convex ones covering the same area: the polygon itself if it's convex,
+// otherwise triangles, by ear clipping. the polygon must not intersect itself.
+func (p *Polygon) Decompose() []*Polygon {
+	if len(p.points) <= 3 || p.turnsOneWay() {
+		return []*Polygon{p}
+	}
+	// work counterclockwise, so an ear turns left
+	pts := append([]Point(nil), p.points...)
+	if p.Area() < 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	var out []*Polygon
+	for len(pts) > 3 {
+		n := len(pts)
+		clipped := false
+		for i := range pts {
+			a, b, c := pts[(i+n-1)%n], pts[i], pts[(i+1)%n]
+			if !isEar(a, b, c, pts) {
+				continue
+			}
+			out = append(out, build(a, b, c))
+			pts = append(pts[:i], pts[i+1:]...)
+			clipped = true
+			break
+		}
+		if !clipped {
+			// only collinear or repeated points left to clip, the rest goes in as it is
+			break
+		}
+	}
+	return append(out, build(pts...))
+}
+
+// isEar is true if the corner a, b, c turns left and no other point is inside it
+func isEar(a, b, c Point, pts []Point) bool {
+	if cross(Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: c.X - b.X, Y: c.Y - b.Y}) <= EPSILON {
+		return false
+	}
+	tri := build(a, b, c)
+	for _, q := range pts {
+		if q == a || q == b || q == c {
+			continue
+		}
+		if tri.Contains(q) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package poly
+
+import (
+	"math"
+	"testing"
+)
+
+// rect returns a w by h rectangle with its lower left corner at x, y
+func rect(x, y, w, h float64) *Polygon {
+	return MustNew(Point{x, y}, Point{x, y + h}, Point{x + w, y + h}, Point{x + w, y})
+}
+
+func TestCollide(t *testing.T) {
+	u := MustNew(Point{0, 0}, Point{0, 10}, Point{4, 10}, Point{4, 5}, Point{6, 5}, Point{6, 10}, Point{10, 10}, Point{10, 0})
+	// a square turned 45°, its corners 1 from its center at 0, 0
+	diamond := MustNew(Point{0, 1}, Point{1, 0}, Point{0, -1}, Point{-1, 0})
+
+	type test struct {
+		name   string
+		a, b   *Polygon
+		hit    bool
+		depth  float64
+		normal Point
+	}
+	tests := []test{
+		{name: "apart", a: rect(0, 0, 1, 1), b: rect(3, 0, 1, 1)},
+		{name: "touching", a: rect(0, 0, 1, 1), b: rect(1, 0, 1, 1)},
+		// pushed back left, the shallow way out
+		{name: "overlap from the left", a: rect(0, 0, 2, 2), b: rect(1.5, -1, 2, 4), hit: true, depth: 0.5, normal: Point{-1, 0}},
+		{name: "overlap from above", a: rect(0, 1.8, 2, 2), b: rect(-1, 0, 4, 2), hit: true, depth: 0.2, normal: Point{0, 1}},
+		// boxes overlap, the shapes don't
+		{name: "diamond beside box", a: diamond, b: rect(0.6, 0.6, 1, 1)},
+		{name: "diamond corner in box", a: diamond, b: rect(0.8, -0.5, 1, 1), hit: true, depth: 0.2, normal: Point{-1, 0}},
+		{name: "diamond edge on box corner", a: diamond, b: rect(0.4, 0.4, 1, 1), hit: true, depth: 0.2 / math.Sqrt2, normal: Point{-math.Sqrt2 / 2, -math.Sqrt2 / 2}},
+		// concave: in the notch of the U isn't hitting it, but the bounding boxes and convex hull overlap
+		{name: "in the notch", a: rect(4.5, 6, 1, 3), b: u},
+		{name: "in the notch, touching its floor", a: rect(4.5, 5, 1, 3), b: u},
+		{name: "into the arm", a: rect(3.5, 6, 1, 3), b: u, hit: true, depth: 0.5, normal: Point{1, 0}},
+		{name: "into the floor", a: rect(4.5, 4.9, 1, 3), b: u, hit: true, depth: 0.1, normal: Point{0, 1}},
+		// a wall thinner than the shape hitting it
+		{name: "thin wall", a: rect(0, 0, 2, 2), b: rect(1, -5, 0.01, 10), hit: true, depth: 0.01, normal: Point{-1, 0}},
+	}
+	for _, test := range tests {
+		got, hit := Collide(test.a, test.b)
+		if hit != test.hit || hit && (math.Abs(got.Depth-test.depth) > 1e-9 || math.Abs(got.Normal.X-test.normal.X) > 1e-9 || math.Abs(got.Normal.Y-test.normal.Y) > 1e-9) {
+			t.Fatalf("%s: Collide(), expected: %t %.3f %v, got: %t %+v", test.name, test.hit, test.depth, test.normal, hit, got)
+		}
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	u := MustNew(Point{0, 0}, Point{0, 10}, Point{4, 10}, Point{4, 5}, Point{6, 5}, Point{6, 10}, Point{10, 10}, Point{10, 0})
+	type test struct {
+		name   string
+		p      *Polygon
+		pieces int
+		area   float64
+	}
+	tests := []test{
+		{name: "convex", p: rect(0, 0, 2, 3), pieces: 1, area: 6},
+		{name: "u", p: u, pieces: 6, area: 90},
+		// the same, the other way around
+		{name: "u clockwise", p: MustNew(Point{10, 0}, Point{10, 10}, Point{6, 10}, Point{6, 5}, Point{4, 5}, Point{4, 10}, Point{0, 10}, Point{0, 0}), pieces: 6, area: 90},
+	}
+	for _, test := range tests {
+		pieces := test.p.Decompose()
+		area := 0.0
+		for _, piece := range pieces {
+			if !piece.IsConvex() {
+				t.Fatalf("%s: Decompose(), expected convex pieces, got: %v", test.name, piece.points)
+			}
+			area += math.Abs(piece.Area())
+		}
+		if len(pieces) != test.pieces || math.Abs(area-test.area) > 1e-9 {
+			t.Fatalf("%s: Decompose(), expected: %d pieces of %.1f, got: %d of %.1f", test.name, test.pieces, test.area, len(pieces), area)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package poly
+
+import (
+	"sync"
+	"testing"
+)
+
+// run with -race: polygons are shared between the game loop and the goroutines sensing and avoiding
+func TestConcurrent(t *testing.T) {
+	u := MustNew(Point{0, 0}, Point{0, 10}, Point{4, 10}, Point{4, 5}, Point{6, 5}, Point{6, 10}, Point{10, 10}, Point{10, 0})
+	walls := room(-20, -20, 40, 40, 1)
+	g := NewGrid(2, []Solid{u, walls})
+	ray := Ray{Origin: Point{5, 8}, Dir: Point{0, -1}}
+
+	// what a single goroutine sees, every goroutine has to see the same
+	wantContains := u.Contains(Point{5, 4})
+	wantHit, _ := u.Raycast(ray, 20)
+	wantNear := g.Query(4, 4, 6, 6)
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if u.Contains(Point{5, 4}) != wantContains {
+					errs <- "Contains"
+					return
+				}
+				if h, _ := u.Raycast(ray, 20); h != wantHit {
+					errs <- "Raycast"
+					return
+				}
+				if _, _, ok := g.Raycast(Ray{Origin: Point{float64(i), 0}, Dir: Point{1, 1}}, 50); !ok {
+					errs <- "Grid.Raycast"
+					return
+				}
+				if got := g.Query(4, 4, 6, 6); len(got) != len(wantNear) {
+					errs <- "Query"
+					return
+				}
+				if _, hit := Collide(rect(float64(i), 0, 1, 1), u); !hit {
+					errs <- "Collide"
+					return
+				}
+				if d := walls.Distance(Point{}); d != 19 {
+					errs <- "Distance"
+					return
+				}
+				u.Edges()
+				u.BoundingBox()
+				walls.Decompose()
+			}
+		}(i % 10)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatalf("%s, expected: the same from every goroutine, got: something else", e)
+	}
+}
+
+func TestNoAllocs(t *testing.T) {
+	p := MustNew(Point{0, 0}, Point{0, 10}, Point{4, 10}, Point{4, 5}, Point{6, 5}, Point{6, 10}, Point{10, 10}, Point{10, 0})
+	ray := Ray{Origin: Point{5, 8}, Dir: Point{0, -1}}
+
+	type test struct {
+		name string
+		f    func()
+	}
+	tests := []test{
+		{name: "BoundingBox", f: func() { p.BoundingBox() }},
+		{name: "Edges", f: func() { p.Edges() }},
+		{name: "Contains", f: func() { p.Contains(Point{5, 4}) }},
+		{name: "Raycast", f: func() { p.Raycast(ray, 20) }},
+		{name: "Distance", f: func() { p.Distance(Point{20, 20}) }},
+		{name: "Area", f: func() { p.Area() }},
+	}
+	for _, test := range tests {
+		if got := testing.AllocsPerRun(100, test.f); got != 0 {
+			t.Fatalf("%s, expected: no allocations, got: %v", test.name, got)
+		}
+	}
+}
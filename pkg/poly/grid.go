@@ -0,0 +1,264 @@
+package poly
+
+// a uniform grid over shapes, so queries only look at the shapes near where they look rather than all of them.
+// each shape is listed in every cell its bounding box touches. cells should be about the size of a typical
+// shape: much smaller and big ones are listed in lots of cells, much bigger and each cell lists lots of them.
+
+import (
+	"math"
+	"sort"
+)
+
+// Solid is anything with an inside that the grid can index: a Polygon, a Shape or a MultiShape
+type Solid interface {
+	BoundingBox() (x1, y1, x2, y2 float64)
+	Contains(q Point) bool
+	Distance(q Point) float64
+	Raycast(r Ray, max float64) (Hit, bool)
+}
+
+type cell struct {
+	x, y int
+}
+
+// Grid finds shapes by where they are. shapes are identified by their index in the slice given to NewGrid.
+type Grid struct {
+	size   float64
+	shapes []Solid
+	cells  map[cell][]int
+	lo, hi cell // the corners of the cells with anything in them
+}
+
+// NewGrid indexes shapes in a grid of square cells size wide
+func NewGrid(size float64, shapes []Solid) *Grid {
+	g := &Grid{size: size, shapes: shapes, cells: map[cell][]int{}}
+	for i, s := range shapes {
+		if empty(s) {
+			continue
+		}
+		x1, y1, x2, y2 := s.BoundingBox()
+		c1, c2 := g.cell(x1, y1), g.cell(x2, y2)
+		if len(g.cells) == 0 {
+			g.lo, g.hi = c1, c2
+		}
+		g.lo = cell{minInt(g.lo.x, c1.x), minInt(g.lo.y, c1.y)}
+		g.hi = cell{maxInt(g.hi.x, c2.x), maxInt(g.hi.y, c2.y)}
+		for x := c1.x; x <= c2.x; x++ {
+			for y := c1.y; y <= c2.y; y++ {
+				g.cells[cell{x, y}] = append(g.cells[cell{x, y}], i)
+			}
+		}
+	}
+	return g
+}
+
+// empty is true for shapes with no points, which are nowhere
+func empty(s Solid) bool {
+	switch s := s.(type) {
+	case *Polygon:
+		return len(s.points) == 0
+	case *Shape:
+		return len(s.outer.points) == 0
+	case MultiShape:
+		for _, sh := range s {
+			if !empty(sh) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (g *Grid) cell(x, y float64) cell {
+	return cell{int(math.Floor(x / g.size)), int(math.Floor(y / g.size))}
+}
+
+// Query returns the shapes whose bounding boxes overlap the box from x1, y1 to x2, y2, edges included,
+// in the order they were given to NewGrid
+func (g *Grid) Query(x1, y1, x2, y2 float64) []int {
+	if len(g.cells) == 0 {
+		return nil
+	}
+	// no need to look through empty cells outside the grid, for big boxes like the whole screen
+	c1, c2 := g.cell(x1, y1), g.cell(x2, y2)
+	c1 = cell{maxInt(c1.x, g.lo.x), maxInt(c1.y, g.lo.y)}
+	c2 = cell{minInt(c2.x, g.hi.x), minInt(c2.y, g.hi.y)}
+	seen := map[int]bool{}
+	var found []int
+	for x := c1.x; x <= c2.x; x++ {
+		for y := c1.y; y <= c2.y; y++ {
+			for _, i := range g.cells[cell{x, y}] {
+				if seen[i] {
+					continue
+				}
+				seen[i] = true
+				bx1, by1, bx2, by2 := g.shapes[i].BoundingBox()
+				if bx1 <= x2 && x1 <= bx2 && by1 <= y2 && y1 <= by2 {
+					found = append(found, i)
+				}
+			}
+		}
+	}
+	sort.Ints(found)
+	return found
+}
+
+// Nearest returns the shape closest to q and how far it is, 0 if q is inside it. false if there are no shapes.
+func (g *Grid) Nearest(q Point) (int, float64, bool) {
+	if len(g.cells) == 0 {
+		return 0, 0, false
+	}
+	// the furthest ring of cells from q worth looking at, past it there are none
+	c := g.cell(q.X, q.Y)
+	maxRing := maxInt(maxInt(absInt(g.lo.x-c.x), absInt(g.hi.x-c.x)), maxInt(absInt(g.lo.y-c.y), absInt(g.hi.y-c.y)))
+	best, bestDist := -1, math.Inf(1)
+	seen := map[int]bool{}
+	visit := func(x, y int) {
+		for _, i := range g.cells[cell{x, y}] {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			if d := g.shapes[i].Distance(q); d < bestDist || d == bestDist && i < best {
+				best, bestDist = i, d
+			}
+		}
+	}
+	for ring := 0; ring <= maxRing; ring++ {
+		// everything in this ring or further out is at least this far away
+		if float64(ring-1)*g.size > bestDist {
+			break
+		}
+		// only the cells on the ring's edge, and only those with shapes around them
+		x1, x2 := maxInt(c.x-ring, g.lo.x), minInt(c.x+ring, g.hi.x)
+		y1, y2 := maxInt(c.y-ring+1, g.lo.y), minInt(c.y+ring-1, g.hi.y)
+		for _, y := range []int{c.y - ring, c.y + ring} {
+			if y >= g.lo.y && y <= g.hi.y {
+				for x := x1; x <= x2; x++ {
+					visit(x, y)
+				}
+			}
+			if ring == 0 {
+				break
+			}
+		}
+		for _, x := range []int{c.x - ring, c.x + ring} {
+			if ring > 0 && x >= g.lo.x && x <= g.hi.x {
+				for y := y1; y <= y2; y++ {
+					visit(x, y)
+				}
+			}
+		}
+	}
+	return best, bestDist, best >= 0
+}
+
+// Raycast returns the shape the ray hits first within max distance, and where. it walks the ray through the grid
+// cell by cell, and stops at the first cell that has a hit no further away than the cell's far side.
+func (g *Grid) Raycast(r Ray, max float64) (int, Hit, bool) {
+	d, ok := r.unit()
+	if !ok {
+		return 0, Hit{}, false
+	}
+	c := g.cell(r.Origin.X, r.Origin.Y)
+	// distance along the ray to the next cell boundary in x and y, and between boundaries
+	stepX, nextX, deltaX := boundary(r.Origin.X, d.X, c.x, g.size)
+	stepY, nextY, deltaY := boundary(r.Origin.Y, d.Y, c.y, g.size)
+
+	best, bestHit := -1, Hit{Distance: math.Inf(1)}
+	seen := map[int]bool{}
+	t := 0.0
+	for t <= max {
+		for _, i := range g.cells[c] {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			if h, ok := g.shapes[i].Raycast(r, max); ok && h.Distance < bestHit.Distance {
+				best, bestHit = i, h
+			}
+		}
+		// on to the next cell
+		if nextX < nextY {
+			t, nextX = nextX, nextX+deltaX
+			c.x += stepX
+		} else {
+			t, nextY = nextY, nextY+deltaY
+			c.y += stepY
+		}
+		if bestHit.Distance <= t || g.leaving(c, stepX, stepY) {
+			break
+		}
+	}
+	return best, bestHit, best >= 0
+}
+
+// leaving is true if a ray in cell c moving by step cells has left the grid for good
+func (g *Grid) leaving(c cell, stepX, stepY int) bool {
+	return c.x > g.hi.x && stepX >= 0 || c.x < g.lo.x && stepX <= 0 ||
+		c.y > g.hi.y && stepY >= 0 || c.y < g.lo.y && stepY <= 0
+}
+
+// boundary returns which way a ray from o with direction d moves through cells along one axis, how far along
+// the ray it is to the first cell boundary, and how far between boundaries
+func boundary(o, d float64, c int, size float64) (step int, next, delta float64) {
+	switch {
+	case d > 0:
+		return 1, (float64(c+1)*size - o) / d, size / d
+	case d < 0:
+		return -1, (float64(c)*size - o) / d, -size / d
+	default:
+		return 0, math.Inf(1), math.Inf(1)
+	}
+}
+
+// Distance returns how far q is from the polygon, 0 if it's inside or on an edge
+func (p *Polygon) Distance(q Point) float64 {
+	if len(p.points) == 0 {
+		return math.Inf(1)
+	}
+	if p.Contains(q) {
+		return 0
+	}
+	if len(p.points) == 1 {
+		return math.Hypot(q.X-p.points[0].X, q.Y-p.points[0].Y)
+	}
+	best := math.Inf(1)
+	for _, e := range p.Edges() {
+		best = math.Min(best, segmentDistance(q, e.A, e.B))
+	}
+	return best
+}
+
+// segmentDistance returns how far q is from the line segment from a to b
+func segmentDistance(q, a, b Point) float64 {
+	e := Point{X: b.X - a.X, Y: b.Y - a.Y}
+	l := dot(e, e)
+	t := 0.0
+	if l > 0 {
+		t = math.Max(0, math.Min(1, dot(Point{X: q.X - a.X, Y: q.Y - a.Y}, e)/l))
+	}
+	return math.Hypot(q.X-(a.X+t*e.X), q.Y-(a.Y+t*e.Y))
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
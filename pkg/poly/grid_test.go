@@ -0,0 +1,226 @@
+package poly
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// scene returns n small boxes scattered over a size by size square, the same ones every time
+func scene(n int, size float64) []*Polygon {
+	r := rand.New(rand.NewSource(23))
+	shapes := make([]*Polygon, n)
+	for i := range shapes {
+		shapes[i] = rect(r.Float64()*size, r.Float64()*size, 0.2+r.Float64(), 0.2+r.Float64())
+	}
+	return shapes
+}
+
+func solids(ps []*Polygon) []Solid {
+	s := make([]Solid, len(ps))
+	for i, p := range ps {
+		s[i] = p
+	}
+	return s
+}
+
+// raycastAll is the brute force version of Grid.Raycast
+func raycastAll(shapes []*Polygon, r Ray, max float64) (int, Hit, bool) {
+	best, bestHit := -1, Hit{}
+	for i, p := range shapes {
+		if h, ok := p.Raycast(r, max); ok && (best < 0 || h.Distance < bestHit.Distance) {
+			best, bestHit = i, h
+		}
+	}
+	return best, bestHit, best >= 0
+}
+
+func TestDistance(t *testing.T) {
+	type test struct {
+		q    Point
+		want float64
+	}
+	tests := []test{
+		{Point{0.5, 0.5}, 0},
+		{Point{1, 0.5}, 0},
+		{Point{3, 0.5}, 2},
+		{Point{0.5, -1}, 1},
+		{Point{4, 5}, 5},
+	}
+	for _, test := range tests {
+		got := rect(0, 0, 1, 1).Distance(test.q)
+		if math.Abs(got-test.want) > EPSILON {
+			t.Fatalf("Distance(%v), expected: %v, got: %v", test.q, test.want, got)
+		}
+	}
+}
+
+func TestGridQuery(t *testing.T) {
+	shapes := []*Polygon{rect(0, 0, 1, 1), rect(5, 5, 1, 1), rect(-3, 0, 10, 1), build()}
+	g := NewGrid(2, solids(shapes))
+
+	type test struct {
+		name           string
+		x1, y1, x2, y2 float64
+		want           []int
+	}
+	tests := []test{
+		{name: "nothing there", x1: 2, y1: 3, x2: 4, y2: 4},
+		{name: "one", x1: 5.5, y1: 5.5, x2: 7, y2: 7, want: []int{1}},
+		{name: "overlapping", x1: 0.5, y1: 0.5, x2: 0.6, y2: 0.6, want: []int{0, 2}},
+		// the long one crosses a cell of its own
+		{name: "long", x1: 3, y1: 0.2, x2: 3.1, y2: 0.3, want: []int{2}},
+		{name: "touching", x1: 6, y1: 6, x2: 8, y2: 8, want: []int{1}},
+		{name: "everything", x1: -100, y1: -100, x2: 100, y2: 100, want: []int{0, 1, 2}},
+	}
+	for _, test := range tests {
+		got := g.Query(test.x1, test.y1, test.x2, test.y2)
+		if len(got) != len(test.want) {
+			t.Fatalf("Query(%v), expected: %v, got: %v", test.name, test.want, got)
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Fatalf("Query(%v), expected: %v, got: %v", test.name, test.want, got)
+			}
+		}
+	}
+}
+
+func TestGridNearest(t *testing.T) {
+	shapes := []*Polygon{rect(0, 0, 1, 1), rect(5, 5, 1, 1), rect(20, 0, 1, 1)}
+	g := NewGrid(2, solids(shapes))
+
+	type test struct {
+		q     Point
+		want  int
+		dist  float64
+		found bool
+	}
+	tests := []test{
+		{q: Point{0.5, 0.5}, want: 0, dist: 0, found: true},
+		{q: Point{3.5, 3.5}, want: 1, dist: math.Sqrt(4.5), found: true},
+		// as far from both, the first one wins
+		{q: Point{3, 3}, want: 0, dist: math.Sqrt(8), found: true},
+		// a long way from everything, it has to look through empty rings
+		{q: Point{40, 0.5}, want: 2, dist: 19, found: true},
+		{q: Point{-50, -50}, want: 0, dist: math.Sqrt(2 * 50 * 50), found: true},
+	}
+	for _, test := range tests {
+		got, dist, found := g.Nearest(test.q)
+		if got != test.want || found != test.found || math.Abs(dist-test.dist) > EPSILON {
+			t.Fatalf("Nearest(%v), expected: %v %v %v, got: %v %v %v", test.q, test.want, test.dist, test.found, got, dist, found)
+		}
+	}
+
+	if _, _, found := NewGrid(2, nil).Nearest(Point{}); found {
+		t.Fatalf("Nearest() on an empty grid, expected: nothing, got: something")
+	}
+
+	// the same as checking everything, from inside the grid and from well outside it
+	shapes = scene(200, 50)
+	g = NewGrid(2, solids(shapes))
+	r := rand.New(rand.NewSource(45))
+	for i := 0; i < 1000; i++ {
+		q := Point{r.Float64()*90 - 20, r.Float64()*90 - 20}
+		want := math.Inf(1)
+		for _, p := range shapes {
+			want = math.Min(want, p.Distance(q))
+		}
+		if _, dist, _ := g.Nearest(q); math.Abs(dist-want) > EPSILON {
+			t.Fatalf("Nearest(%v), expected: %v, got: %v", q, want, dist)
+		}
+	}
+}
+
+func TestGridRaycast(t *testing.T) {
+	shapes := scene(200, 50)
+	g := NewGrid(2, solids(shapes))
+	r := rand.New(rand.NewSource(45))
+	// the grid must find the same thing as checking everything, from anywhere in any direction, including outside
+	for i := 0; i < 1000; i++ {
+		ray := Ray{Origin: Point{r.Float64()*70 - 10, r.Float64()*70 - 10}, Dir: Point{r.Float64() - 0.5, r.Float64() - 0.5}}
+		max := r.Float64() * 30
+		if i%10 == 0 {
+			max = math.Inf(1)
+		}
+		want, wantHit, wantOk := raycastAll(shapes, ray, max)
+		got, gotHit, gotOk := g.Raycast(ray, max)
+		if gotOk != wantOk || gotOk && math.Abs(gotHit.Distance-wantHit.Distance) > EPSILON {
+			t.Fatalf("Raycast(%v, %v), expected: %v %v %v, got: %v %v %v", ray, max, want, wantHit, wantOk, got, gotHit, gotOk)
+		}
+	}
+
+	// straight along a row of cells
+	g = NewGrid(1, []Solid{rect(10, 0.2, 1, 0.5)})
+	for _, dir := range []Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		_, _, ok := g.Raycast(Ray{Origin: Point{0, 0.5}, Dir: dir}, math.Inf(1))
+		if ok != (dir == Point{1, 0}) {
+			t.Fatalf("Raycast(%v), expected: %v, got: %v", dir, dir == Point{1, 0}, ok)
+		}
+	}
+}
+
+func BenchmarkRaycast(b *testing.B) {
+	shapes := scene(1000, 100)
+	g := NewGrid(2, solids(shapes))
+	r := rand.New(rand.NewSource(45))
+	rays := make([]Ray, 256)
+	for i := range rays {
+		rays[i] = Ray{Origin: Point{r.Float64() * 100, r.Float64() * 100}, Dir: Point{r.Float64() - 0.5, r.Float64() - 0.5}}
+	}
+
+	b.Run("grid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g.Raycast(rays[i%len(rays)], 10)
+		}
+	})
+	b.Run("all", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			raycastAll(shapes, rays[i%len(rays)], 10)
+		}
+	})
+}
+
+func BenchmarkQuery(b *testing.B) {
+	shapes := scene(1000, 100)
+	g := NewGrid(2, solids(shapes))
+
+	b.Run("grid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x := float64(i % 100)
+			g.Query(x, x, x+2, x+2)
+		}
+	})
+	b.Run("all", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x := float64(i % 100)
+			var found []int
+			for j, p := range shapes {
+				bx1, by1, bx2, by2 := p.BoundingBox()
+				if bx1 <= x+2 && x <= bx2 && by1 <= x+2 && x <= by2 {
+					found = append(found, j)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkNearest(b *testing.B) {
+	shapes := scene(1000, 100)
+	g := NewGrid(2, solids(shapes))
+
+	b.Run("grid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g.Nearest(Point{float64(i % 100), float64(i % 37)})
+		}
+	})
+	b.Run("all", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			q := Point{float64(i % 100), float64(i % 37)}
+			best := math.Inf(1)
+			for _, p := range shapes {
+				best = math.Min(best, p.Distance(q))
+			}
+		}
+	})
+}
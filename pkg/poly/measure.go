@@ -0,0 +1,211 @@
+package poly
+
+// properties of a polygon's shape: how big it is, where its middle is, which way its points go around, and
+// whether it's convex or crosses over itself. all with Y up, so counterclockwise is positive.
+
+import (
+	"math"
+	"sort"
+)
+
+type Orientation int
+
+const (
+	// COLLINEAR is a polygon with no area, all its points on a line
+	COLLINEAR Orientation = iota
+	COUNTERCLOCKWISE
+	CLOCKWISE
+)
+
+func (o Orientation) String() string {
+	switch o {
+	case COUNTERCLOCKWISE:
+		return "counterclockwise"
+	case CLOCKWISE:
+		return "clockwise"
+	default:
+		return "collinear"
+	}
+}
+
+// Area returns the polygon's area, positive if its points go counterclockwise and negative if clockwise
+func (p *Polygon) Area() float64 {
+	return area(p.points)
+}
+
+func area(points []Point) float64 {
+	a := 0.0
+	for i, pt := range points {
+		q := points[(i+1)%len(points)]
+		a += pt.X*q.Y - q.X*pt.Y
+	}
+	return a / 2
+}
+
+// Perimeter returns the total length of the polygon's edges
+func (p *Polygon) Perimeter() float64 {
+	l := 0.0
+	for _, e := range p.Edges() {
+		l += math.Hypot(e.B.X-e.A.X, e.B.Y-e.A.Y)
+	}
+	return l
+}
+
+// Centroid returns the polygon's center of mass. one with no area has none, it gets the average of its points.
+func (p *Polygon) Centroid() Point {
+	if len(p.points) == 0 {
+		return Point{}
+	}
+	a := p.Area()
+	if a == 0 {
+		return p.center()
+	}
+	// measured from the first point, so polygons far from the origin don't lose precision
+	o := p.points[0]
+	var c Point
+	for i, pt := range p.points {
+		q := p.points[(i+1)%len(p.points)]
+		x1, y1, x2, y2 := pt.X-o.X, pt.Y-o.Y, q.X-o.X, q.Y-o.Y
+		w := x1*y2 - x2*y1
+		c.X += (x1 + x2) * w
+		c.Y += (y1 + y2) * w
+	}
+	return Point{X: o.X + c.X/(6*a), Y: o.Y + c.Y/(6*a)}
+}
+
+// Orientation returns which way the polygon's points go around
+func (p *Polygon) Orientation() Orientation {
+	a := p.Area()
+	switch {
+	case a > 0:
+		return COUNTERCLOCKWISE
+	case a < 0:
+		return CLOCKWISE
+	default:
+		return COLLINEAR
+	}
+}
+
+// Oriented returns the polygon with its points going around the given way, the polygon itself if they already do
+func (p *Polygon) Oriented(o Orientation) *Polygon {
+	cur := p.Orientation()
+	if cur == o || cur == COLLINEAR || o == COLLINEAR {
+		return p
+	}
+	pts := p.Points()
+	for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+		pts[i], pts[j] = pts[j], pts[i]
+	}
+	return build(pts...)
+}
+
+// IsConvex is true if the polygon is simple and turns the same way at every point. points along a straight edge
+// are fine.
+func (p *Polygon) IsConvex() bool {
+	return len(p.points) >= 3 && p.turnsOneWay() && p.IsSimple()
+}
+
+// turnsOneWay is true if the polygon turns the same way at every point. a star does too, but isn't convex.
+func (p *Polygon) turnsOneWay() bool {
+	n := len(p.points)
+	sign := 0.0
+	for i := range p.points {
+		a, b, c := p.points[i], p.points[(i+1)%n], p.points[(i+2)%n]
+		turn := cross(Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: c.X - b.X, Y: c.Y - b.Y})
+		if math.Abs(turn) <= EPSILON {
+			continue
+		}
+		if sign != 0 && (turn > 0) != (sign > 0) {
+			return false
+		}
+		sign = turn
+	}
+	return true
+}
+
+// IsSimple is true if the polygon's edges only meet their neighbours, at the points they share. a point used
+// twice, an edge folding back over the one before it, or two edges crossing or touching all make it not simple.
+func (p *Polygon) IsSimple() bool {
+	n := len(p.points)
+	if n < 3 {
+		return false
+	}
+	edges := p.Edges()
+	for i, e := range edges {
+		if e.A == e.B {
+			return false
+		}
+		for j := i + 1; j < n; j++ {
+			f := edges[j]
+			switch {
+			case j == i+1:
+				// e ends where f starts, they mustn't overlap beyond that
+				if onSegment(f.B, e.A, e.B) || onSegment(e.A, f.A, f.B) {
+					return false
+				}
+			case i == 0 && j == n-1:
+				// f ends where e starts
+				if onSegment(f.A, e.A, e.B) || onSegment(e.B, f.A, f.B) {
+					return false
+				}
+			case segmentsIntersect(e.A, e.B, f.A, f.B):
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// segmentsIntersect is true if the line segments from a to b and from c to d cross or touch
+func segmentsIntersect(a, b, c, d Point) bool {
+	d1 := cross(Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: c.X - a.X, Y: c.Y - a.Y})
+	d2 := cross(Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: d.X - a.X, Y: d.Y - a.Y})
+	d3 := cross(Point{X: d.X - c.X, Y: d.Y - c.Y}, Point{X: a.X - c.X, Y: a.Y - c.Y})
+	d4 := cross(Point{X: d.X - c.X, Y: d.Y - c.Y}, Point{X: b.X - c.X, Y: b.Y - c.Y})
+	if (d1 > 0 && d2 < 0 || d1 < 0 && d2 > 0) && (d3 > 0 && d4 < 0 || d3 < 0 && d4 > 0) {
+		return true
+	}
+	// touching, or collinear and overlapping
+	return onSegment(c, a, b) || onSegment(d, a, b) || onSegment(a, c, d) || onSegment(b, c, d)
+}
+
+// ConvexHull returns the smallest convex polygon around the points, counterclockwise starting from the lowest,
+// leftmost one, without points along its edges. fewer than 3 points, or all of them on a line, give a polygon
+// with no area.
+func ConvexHull(points ...Point) *Polygon {
+	pts := append([]Point(nil), points...)
+	// left to right, bottom to top where they're level
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i].X < pts[j].X || pts[i].X == pts[j].X && pts[i].Y < pts[j].Y
+	})
+	// drop repeated points, sorting put them next to each other
+	uniq := pts[:0]
+	for i, pt := range pts {
+		if i == 0 || pt != pts[i-1] {
+			uniq = append(uniq, pt)
+		}
+	}
+	pts = uniq
+	if len(pts) < 3 {
+		return build(pts...)
+	}
+	// andrew's monotone chain: the lower half left to right, then the upper half back, each only turning left
+	hull := make([]Point, 0, 2*len(pts))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, pt := range pts {
+			for len(hull) >= start+2 && cross(
+				Point{X: hull[len(hull)-1].X - hull[len(hull)-2].X, Y: hull[len(hull)-1].Y - hull[len(hull)-2].Y},
+				Point{X: pt.X - hull[len(hull)-1].X, Y: pt.Y - hull[len(hull)-1].Y}) <= EPSILON {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, pt)
+		}
+		// the last point is the first of the other half
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return build(counterclockwiseFromBottom(hull)...)
+}
@@ -0,0 +1,131 @@
+package poly
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	type test struct {
+		name   string
+		points []Point
+		err    error
+	}
+	tests := []test{
+		{name: "square", points: []Point{{0, 0}, {0, 1}, {1, 1}, {1, 0}}},
+		{name: "counterclockwise", points: []Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}},
+		{name: "point along an edge", points: []Point{{0, 0}, {0, 1}, {1, 1}, {1, 0.5}, {1, 0}}},
+		{name: "nothing", err: ErrTooFewPoints},
+		{name: "segment", points: []Point{{0, 0}, {1, 1}}, err: ErrTooFewPoints},
+		{name: "flat", points: []Point{{0, 0}, {1, 1}, {2, 2}}, err: ErrNoArea},
+		{name: "nan", points: []Point{{0, 0}, {0, 1}, {math.NaN(), 1}}, err: ErrNotFinite},
+		{name: "infinite", points: []Point{{0, 0}, {0, 1}, {math.Inf(1), 1}}, err: ErrNotFinite},
+		{name: "bowtie", points: []Point{{0, 0}, {1, 1}, {1, 0}, {0, 1}}, err: ErrSelfIntersecting},
+		{name: "repeated point", points: []Point{{0, 0}, {0, 0}, {0, 1}, {1, 1}}, err: ErrSelfIntersecting},
+		{name: "spike folding back", points: []Point{{0, 0}, {0, 1}, {1, 1}, {3, 1}, {2, 1}, {1, 0}}, err: ErrSelfIntersecting},
+		{name: "touching itself", points: []Point{{0, 0}, {4, 0}, {4, 4}, {2, 0}, {0, 4}}, err: ErrSelfIntersecting},
+	}
+	for _, test := range tests {
+		p, err := New(test.points...)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%s: New(%v), expected: %v, got: %v", test.name, test.points, test.err, err)
+		}
+		if err == nil && len(p.Points()) != len(test.points) {
+			t.Fatalf("%s: New(%v), expected: the same points, got: %v", test.name, test.points, p.Points())
+		}
+	}
+}
+
+func TestEdges(t *testing.T) {
+	for _, p := range []*Polygon{build(), build(Point{1, 1}), &Polygon{}} {
+		if got := p.Edges(); len(got) != 0 {
+			t.Fatalf("Edges(%v), expected: none, got: %v", p.points, got)
+		}
+	}
+	if got := build(Point{0, 0}, Point{1, 1}).Edges(); len(got) != 2 {
+		t.Fatalf("Edges(segment), expected: 2, got: %v", got)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	type test struct {
+		name        string
+		p           *Polygon
+		area        float64
+		perimeter   float64
+		centroid    Point
+		orientation Orientation
+		simple      bool
+		convex      bool
+	}
+	tests := []test{
+		{name: "square clockwise", p: rect(0, 0, 2, 2), area: -4, perimeter: 8, centroid: Point{1, 1}, orientation: CLOCKWISE, simple: true, convex: true},
+		{name: "square counterclockwise", p: rect(0, 0, 2, 2).Oriented(COUNTERCLOCKWISE), area: 4, perimeter: 8, centroid: Point{1, 1}, orientation: COUNTERCLOCKWISE, simple: true, convex: true},
+		// far from the origin
+		{name: "square far away", p: rect(1e6, 1e6, 2, 2), area: -4, perimeter: 8, centroid: Point{1e6 + 1, 1e6 + 1}, orientation: CLOCKWISE, simple: true, convex: true},
+		{name: "triangle", p: MustNew(Point{0, 0}, Point{3, 0}, Point{0, 3}), area: 4.5, perimeter: 6 + 3*math.Sqrt2, centroid: Point{1, 1}, orientation: COUNTERCLOCKWISE, simple: true, convex: true},
+		// an L: a 2x1 bar along the bottom and a 1x1 block on its left end, centroid weighted towards the bar
+		{name: "l", p: MustNew(Point{0, 0}, Point{2, 0}, Point{2, 1}, Point{1, 1}, Point{1, 2}, Point{0, 2}), area: 3, perimeter: 8, centroid: Point{5.0 / 6, 5.0 / 6}, orientation: COUNTERCLOCKWISE, simple: true},
+		{name: "point along an edge", p: MustNew(Point{0, 0}, Point{1, 0}, Point{2, 0}, Point{2, 2}, Point{0, 2}), area: 4, perimeter: 8, centroid: Point{1, 1}, orientation: COUNTERCLOCKWISE, simple: true, convex: true},
+		// turns the same way at every point, but goes around twice, so the pentagon in the middle counts twice
+		{name: "star", p: build(Point{0, 10}, Point{6, -8}, Point{-9.5, 3}, Point{9.5, 3}, Point{-6, -8}), area: -146.5,
+			perimeter: 12*math.Sqrt(10) + 2*math.Sqrt(361.25) + 19, centroid: Point{0, 2.0 / 879}, orientation: CLOCKWISE},
+		{name: "flat", p: build(Point{0, 0}, Point{1, 0}, Point{2, 0}), perimeter: 4, centroid: Point{1, 0}, orientation: COLLINEAR},
+	}
+	for _, test := range tests {
+		if got := test.p.Area(); math.Abs(got-test.area) > 1e-6 {
+			t.Fatalf("%s: Area(), expected: %v, got: %v", test.name, test.area, got)
+		}
+		if got := test.p.Perimeter(); math.Abs(got-test.perimeter) > 1e-6 {
+			t.Fatalf("%s: Perimeter(), expected: %v, got: %v", test.name, test.perimeter, got)
+		}
+		if got := test.p.Centroid(); !pointEq(got, test.centroid) {
+			t.Fatalf("%s: Centroid(), expected: %v, got: %v", test.name, test.centroid, got)
+		}
+		if got := test.p.IsSimple(); got != test.simple {
+			t.Fatalf("%s: IsSimple(), expected: %v, got: %v", test.name, test.simple, got)
+		}
+		if got := test.p.Orientation(); got != test.orientation {
+			t.Fatalf("%s: Orientation(), expected: %v, got: %v", test.name, test.orientation, got)
+		}
+		if got := test.p.IsConvex(); got != test.convex {
+			t.Fatalf("%s: IsConvex(), expected: %v, got: %v", test.name, test.convex, got)
+		}
+		// turning it around only changes the sign of the area
+		o := test.p.Oriented(CLOCKWISE)
+		if test.orientation != COLLINEAR && (o.Orientation() != CLOCKWISE || math.Abs(math.Abs(o.Area())-math.Abs(test.p.Area())) > 1e-6) {
+			t.Fatalf("%s: Oriented(clockwise), expected: clockwise, got: %v", test.name, o.Orientation())
+		}
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	type test struct {
+		name   string
+		points []Point
+		want   []Point
+	}
+	tests := []test{
+		{name: "nothing"},
+		{name: "one point", points: []Point{{1, 1}, {1, 1}}, want: []Point{{1, 1}}},
+		{name: "square", points: []Point{{0, 2}, {2, 2}, {0, 0}, {2, 0}}, want: []Point{{0, 0}, {2, 0}, {2, 2}, {0, 2}}},
+		{name: "points inside and along edges", points: []Point{{1, 1}, {0, 0}, {1, 0}, {2, 0}, {2, 2}, {0.5, 1.5}, {0, 2}, {0, 1}},
+			want: []Point{{0, 0}, {2, 0}, {2, 2}, {0, 2}}},
+		{name: "concave", points: []Point{{0, 0}, {0, 10}, {4, 10}, {4, 5}, {6, 5}, {6, 10}, {10, 10}, {10, 0}},
+			want: []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}},
+		// starts from the lowest, not the leftmost
+		{name: "diamond", points: []Point{{-1, 0}, {0, 1}, {1, 0}, {0, -1}}, want: []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}},
+	}
+	for _, test := range tests {
+		got := ConvexHull(test.points...).Points()
+		if len(got) != len(test.want) {
+			t.Fatalf("%s: ConvexHull(%v), expected: %v, got: %v", test.name, test.points, test.want, got)
+		}
+		for i := range got {
+			if !pointEq(got[i], test.want[i]) {
+				t.Fatalf("%s: ConvexHull(%v), expected: %v, got: %v", test.name, test.points, test.want, got)
+			}
+		}
+	}
+}
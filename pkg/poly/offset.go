@@ -0,0 +1,171 @@
+package poly
+
+// growing and shrinking shapes. inflating an obstacle by the chair's half width plus a margin turns "does the chair
+// fit" into "is the chair's center outside the inflated obstacle", a single point query.
+
+import (
+	"math"
+)
+
+type Join int
+
+const (
+	// JOIN_MITER extends both edges until they meet, cut off at the miter limit for sharp corners
+	JOIN_MITER Join = iota
+	// JOIN_ROUND goes around corners on an arc, like a circle rolled along the outside
+	JOIN_ROUND
+)
+
+const (
+	// MITER_LIMIT is how far a miter corner can stick out, as a multiple of the offset, before it's cut off
+	MITER_LIMIT = 2.0
+	// ROUND_STEP_RAD is the largest angle a single segment of a round join covers
+	ROUND_STEP_RAD = math.Pi / 16
+)
+
+// Offset returns the polygon grown by d, or shrunk for a negative d, keeping the order of its points.
+// sharp corners get two points when the miter would be too long, round ones get a point every ROUND_STEP_RAD.
+// shrinking by more than a feature is wide, or growing a concave polygon by more than its notches are wide, can
+// leave the result intersecting itself.
+func (p *Polygon) Offset(d float64, join Join) *Polygon {
+	n := len(p.points)
+	if n < 3 || d == 0 {
+		return build(p.Points()...)
+	}
+	// with Y up, outward is to the right of the edges going counterclockwise, to the left going clockwise
+	side := 1.0
+	if p.Area() < 0 {
+		side = -1.0
+	}
+	var out []Point
+	for i := range p.points {
+		prev, cur, next := p.points[(i+n-1)%n], p.points[i], p.points[(i+1)%n]
+		n1, ok1 := edgeNormal(prev, cur, side)
+		n2, ok2 := edgeNormal(cur, next, side)
+		if !ok1 || !ok2 {
+			// a repeated point, the other copy takes care of the corner
+			continue
+		}
+		a := Point{X: cur.X + n1.X*d, Y: cur.Y + n1.Y*d}
+		b := Point{X: cur.X + n2.X*d, Y: cur.Y + n2.Y*d}
+		e1, e2 := Point{X: cur.X - prev.X, Y: cur.Y - prev.Y}, Point{X: next.X - cur.X, Y: next.Y - cur.Y}
+		turn := cross(e1, e2)
+		// a convex corner turns the way the polygon goes around. growing, the offset edges leave a gap around it
+		// to fill with a join. shrinking, it's the concave corners that do
+		outer := turn*side*d > 0
+		cos := dot(n1, n2)
+		switch {
+		case math.Abs(turn) <= EPSILON*math.Max(math.Hypot(e1.X, e1.Y)*math.Hypot(e2.X, e2.Y), 1):
+			// straight on, or folding back on itself
+			if cos > 0 {
+				out = append(out, a)
+			} else {
+				out = append(out, a, b)
+			}
+		case outer && join == JOIN_ROUND:
+			out = append(out, arc(cur, n1, n2, d)...)
+		default:
+			// the offset edges meet where both are d away, along the bisector of the normals
+			bis := Point{X: n1.X + n2.X, Y: n1.Y + n2.Y}
+			l := 1 + cos // |bis|^2 / 2
+			m := Point{X: cur.X + bis.X*d/l, Y: cur.Y + bis.Y*d/l}
+			if outer && math.Hypot(m.X-cur.X, m.Y-cur.Y) > MITER_LIMIT*math.Abs(d) {
+				out = append(out, a, b)
+			} else {
+				out = append(out, m)
+			}
+		}
+	}
+	return build(out...)
+}
+
+// edgeNormal returns the unit normal of the edge from a to b on the given side, 1 for right and -1 for left
+func edgeNormal(a, b Point, side float64) (Point, bool) {
+	l := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if l == 0 {
+		return Point{}, false
+	}
+	return Point{X: side * (b.Y - a.Y) / l, Y: side * -(b.X - a.X) / l}, true
+}
+
+// arc returns the points on a circle of radius |d| around c from c + d*n1 to c + d*n2, the short way around
+func arc(c, n1, n2 Point, d float64) []Point {
+	from := math.Atan2(n1.Y, n1.X)
+	sweep := math.Atan2(cross(n1, n2), dot(n1, n2))
+	steps := int(math.Ceil(math.Abs(sweep) / ROUND_STEP_RAD))
+	if steps < 1 {
+		steps = 1
+	}
+	pts := make([]Point, 0, steps+1)
+	for i := 0; i <= steps; i++ {
+		s, co := math.Sincos(from + sweep*float64(i)/float64(steps))
+		pts = append(pts, Point{X: c.X + co*d, Y: c.Y + s*d})
+	}
+	return pts
+}
+
+// MinkowskiSum returns the convex polygon covering every a + b for points a in the first and b in the second
+// polygon, both of which must be convex: the first one swept around the outline of the second one. the result
+// goes counterclockwise.
+func MinkowskiSum(a, b *Polygon) *Polygon {
+	pa, pb := counterclockwiseFromBottom(a.points), counterclockwiseFromBottom(b.points)
+	if len(pa) == 0 || len(pb) == 0 {
+		return build()
+	}
+	var out []Point
+	i, j := 0, 0
+	// walk both outlines once, always along the edge that turns the least
+	for i < len(pa) || j < len(pb) {
+		out = append(out, Point{X: pa[i%len(pa)].X + pb[j%len(pb)].X, Y: pa[i%len(pa)].Y + pb[j%len(pb)].Y})
+		ea := Point{X: pa[(i+1)%len(pa)].X - pa[i%len(pa)].X, Y: pa[(i+1)%len(pa)].Y - pa[i%len(pa)].Y}
+		eb := Point{X: pb[(j+1)%len(pb)].X - pb[j%len(pb)].X, Y: pb[(j+1)%len(pb)].Y - pb[j%len(pb)].Y}
+		c := cross(ea, eb)
+		switch {
+		case j >= len(pb) || i < len(pa) && c > EPSILON:
+			i++
+		case i >= len(pa) || c < -EPSILON:
+			j++
+		default:
+			// parallel edges, take both at once
+			i++
+			j++
+		}
+	}
+	return build(dropCollinear(out)...)
+}
+
+// counterclockwiseFromBottom returns the points counterclockwise, starting at the lowest one, the leftmost of
+// those if there are several
+func counterclockwiseFromBottom(points []Point) []Point {
+	pts := append([]Point(nil), points...)
+	if area(pts) < 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	first := 0
+	for i, pt := range pts {
+		if pt.Y < pts[first].Y || pt.Y == pts[first].Y && pt.X < pts[first].X {
+			first = i
+		}
+	}
+	return append(append([]Point(nil), pts[first:]...), pts[:first]...)
+}
+
+// dropCollinear removes points in the middle of a straight edge, and repeated points
+func dropCollinear(points []Point) []Point {
+	var out []Point
+	n := len(points)
+	for i, cur := range points {
+		prev, next := points[(i+n-1)%n], points[(i+1)%n]
+		if cur == prev {
+			continue
+		}
+		if n > 2 && math.Abs(cross(Point{X: cur.X - prev.X, Y: cur.Y - prev.Y}, Point{X: next.X - cur.X, Y: next.Y - cur.Y})) <= EPSILON &&
+			dot(Point{X: cur.X - prev.X, Y: cur.Y - prev.Y}, Point{X: next.X - cur.X, Y: next.Y - cur.Y}) > 0 {
+			continue
+		}
+		out = append(out, cur)
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package poly
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOffset(t *testing.T) {
+	square := rect(0, 0, 10, 10)
+	u := MustNew(Point{0, 0}, Point{0, 10}, Point{4, 10}, Point{4, 5}, Point{6, 5}, Point{6, 10}, Point{10, 10}, Point{10, 0})
+	// a thin spike, its tip is sharper than the miter limit allows, so it gets cut off just past the tip
+	spike := MustNew(Point{0, 0}, Point{10, 1}, Point{0, 2})
+
+	type test struct {
+		name   string
+		p      *Polygon
+		d      float64
+		join   Join
+		points int
+		area   float64
+		in     []Point // inside the result
+		out    []Point // outside the result
+	}
+	tests := []test{
+		{name: "miter", p: square, d: 1, join: JOIN_MITER, points: 4, area: 144, in: []Point{{-0.9, -0.9}, {10.9, 5}}, out: []Point{{-1.1, 5}}},
+		// the same the other way around
+		{name: "miter, counterclockwise", p: rect(0, 0, 10, 10).Transform(Scale(1, -1)).Transform(Translate(0, 10)), d: 1, join: JOIN_MITER, points: 4, area: 144},
+		// the corners are quarter circles, 8 segments each
+		{name: "round", p: square, d: 1, join: JOIN_ROUND, points: 36, area: 100 + 40 + 16*math.Sin(math.Pi/16), in: []Point{{-0.6, -0.6}}, out: []Point{{-0.9, -0.9}}},
+		{name: "shrink", p: square, d: -1, join: JOIN_ROUND, points: 4, area: 64, in: []Point{{1.1, 1.1}}, out: []Point{{0.9, 5}}},
+		// the notch narrows from 2 to 1 wide and its floor rises
+		{name: "concave", p: u, d: 0.5, join: JOIN_MITER, points: 8, area: 11*11 - 1*5, in: []Point{{4.4, 9}, {5, 5.4}}, out: []Point{{5, 5.6}}},
+		{name: "concave, round", p: u, d: 0.5, join: JOIN_ROUND, in: []Point{{4.4, 9}, {5, 5.4}, {-0.4, -0.2}}, out: []Point{{5, 5.6}, {-0.4, -0.4}}},
+		{name: "miter limit", p: spike, d: 0.5, join: JOIN_MITER, points: 4, in: []Point{{10.04, 1}}, out: []Point{{10.1, 1}}},
+	}
+	for _, test := range tests {
+		got := test.p.Offset(test.d, test.join)
+		if test.points > 0 && len(got.points) != test.points {
+			t.Fatalf("%s: Offset(%.1f), expected: %d points, got: %v", test.name, test.d, test.points, got.points)
+		}
+		if test.area > 0 && math.Abs(math.Abs(got.Area())-test.area) > 1e-9 {
+			t.Fatalf("%s: Offset(%.1f), expected: an area of %.3f, got: %.3f", test.name, test.d, test.area, math.Abs(got.Area()))
+		}
+		for _, q := range test.in {
+			if !got.Contains(q) {
+				t.Fatalf("%s: Offset(%.1f), expected %v inside, got: %v", test.name, test.d, q, got.points)
+			}
+		}
+		for _, q := range test.out {
+			if got.Contains(q) {
+				t.Fatalf("%s: Offset(%.1f), expected %v outside, got: %v", test.name, test.d, q, got.points)
+			}
+		}
+	}
+}
+
+func TestMinkowskiSum(t *testing.T) {
+	diamond := MustNew(Point{0, 1}, Point{1, 0}, Point{0, -1}, Point{-1, 0})
+	type test struct {
+		name string
+		a, b *Polygon
+		want []Point // counterclockwise from the bottom
+	}
+	tests := []test{
+		{name: "squares", a: rect(0, 0, 2, 2), b: rect(-1, -1, 2, 2), want: []Point{{-1, -1}, {3, -1}, {3, 3}, {-1, 3}}},
+		// a square around a diamond gives an octagon
+		{name: "octagon", a: rect(-1, -1, 2, 2), b: diamond, want: []Point{{-1, -2}, {1, -2}, {2, -1}, {2, 1}, {1, 2}, {-1, 2}, {-2, 1}, {-2, -1}}},
+		{name: "triangle and point", a: MustNew(Point{0, 0}, Point{0, 1}, Point{1, 0}), b: build(Point{5, 5}), want: []Point{{5, 5}, {6, 5}, {5, 6}}},
+	}
+	for _, test := range tests {
+		got := MinkowskiSum(test.a, test.b).Points()
+		ok := len(got) == len(test.want)
+		for i := 0; ok && i < len(got); i++ {
+			ok = pointEq(got[i], test.want[i])
+		}
+		if !ok {
+			t.Fatalf("%s: MinkowskiSum(), expected: %v, got: %v", test.name, test.want, got)
+		}
+	}
+}
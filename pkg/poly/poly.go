@@ -4,6 +4,8 @@ package poly
 // http://philliplemons.com/posts/ray-casting-algorithm
 
 import (
+	"errors"
+	"fmt"
 	"math"
 )
 
@@ -11,44 +13,86 @@ type Point struct {
 	X, Y float64
 }
 
+// Edge is the line segment from A to B
+type Edge struct {
+	A, B Point
+}
+
+// Polygon never changes once it's made. its edges and bounding box are worked out up front, so it can be shared
+// between goroutines without locking and looking at it doesn't allocate.
 type Polygon struct {
 	points []Point
-	edges  [][]Point
-	bbox   []float64
+	edges  []Edge
+	bbox   [4]float64
 }
 
-// New takes as its argument a list of points in clockwise order
-func New(points ...Point) *Polygon {
-	return &Polygon{
-		points: points,
-		edges:  make([][]Point, 0),
-		bbox:   make([]float64, 0),
+var (
+	ErrTooFewPoints     = errors.New("poly: fewer than 3 points")
+	ErrNotFinite        = errors.New("poly: point is not finite")
+	ErrNoArea           = errors.New("poly: all points are on a line")
+	ErrSelfIntersecting = errors.New("poly: edges intersect")
+)
+
+// New makes a polygon from its points, in either order, see Orientation. it returns an error for anything that
+// isn't a simple polygon: fewer than 3 points, points at infinity, all points on a line, or edges that cross,
+// touch or overlap each other.
+func New(points ...Point) (*Polygon, error) {
+	if len(points) < 3 {
+		return nil, ErrTooFewPoints
+	}
+	for i, pt := range points {
+		if math.IsNaN(pt.X) || math.IsNaN(pt.Y) || math.IsInf(pt.X, 0) || math.IsInf(pt.Y, 0) {
+			return nil, fmt.Errorf("%w: %v at %d", ErrNotFinite, pt, i)
+		}
+	}
+	p := build(points...)
+	x1, y1, x2, y2 := p.BoundingBox()
+	// the hull's area rather than the polygon's, a bowtie's two halves cancel out
+	if math.Abs(ConvexHull(points...).Area()) <= EPSILON*math.Max((x2-x1)*(x2-x1)+(y2-y1)*(y2-y1), 1) {
+		return nil, ErrNoArea
+	}
+	if !p.IsSimple() {
+		return nil, ErrSelfIntersecting
 	}
+	return p, nil
 }
 
-func (p *Polygon) BoundingBox() (x1, y1, x2, y2 float64) {
-	if len(p.bbox) == 4 {
-		return p.bbox[0], p.bbox[1], p.bbox[2], p.bbox[3]
+// MustNew is New for shapes written out in code, it panics if they're wrong
+func MustNew(points ...Point) *Polygon {
+	p, err := New(points...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// build makes a polygon without checking it, for shapes made from ones that were checked. they can still be
+// degenerate, a polygon scaled by 0 or shrunk by too much, so everything has to cope with that.
+func build(points ...Point) *Polygon {
+	p := &Polygon{points: append([]Point(nil), points...)}
+	if len(points) == 0 {
+		return p
 	}
 	// find min and max x and y
-	x1, y1, x2, y2 = p.points[0].X, p.points[0].Y, p.points[0].X, p.points[0].Y
-	for _, pt := range p.points {
-		if pt.X < x1 {
-			x1 = pt.X
-		}
-		if pt.Y < y1 {
-			y1 = pt.Y
-		}
-		if pt.X >= x2 {
-			x2 = pt.X
-		}
-		if pt.Y >= y2 {
-			y2 = pt.Y
+	x1, y1, x2, y2 := points[0].X, points[0].Y, points[0].X, points[0].Y
+	for _, pt := range points {
+		x1, y1 = math.Min(x1, pt.X), math.Min(y1, pt.Y)
+		x2, y2 = math.Max(x2, pt.X), math.Max(y2, pt.Y)
+	}
+	p.bbox = [4]float64{x1, y1, x2, y2}
+	// each point to the next, ending with the last back to the first
+	if len(points) >= 2 {
+		p.edges = make([]Edge, len(points))
+		for i := range points {
+			p.edges[i] = Edge{A: points[i], B: points[(i+1)%len(points)]}
 		}
 	}
-	// log.Printf("x1: %.2f, y1: %.2f, x2: %.2f, y2: %.2f", x1, y1, x2, y2)
-	p.bbox = []float64{x1, y1, x2, y2}
-	return x1, y1, x2, y2
+	return p
+}
+
+// BoundingBox returns the corners of the smallest box around the polygon, all 0 if it has no points
+func (p *Polygon) BoundingBox() (x1, y1, x2, y2 float64) {
+	return p.bbox[0], p.bbox[1], p.bbox[2], p.bbox[3]
 }
 
 func (p *Polygon) BoundingBoxOverlaps(sMinX, sMinY, sMaxX, sMaxY float64) bool {
@@ -56,76 +100,54 @@ func (p *Polygon) BoundingBoxOverlaps(sMinX, sMinY, sMaxX, sMaxY float64) bool {
 	return rMinX < sMaxX && sMinX < rMaxX && rMinY < sMaxY && sMinY < rMaxY
 }
 
-func (p *Polygon) Edges() [][]Point {
-	// avoid doing this work if it's already been done
-	if len(p.edges) != 0 {
-		return p.edges
-	}
-	if len(p.points) < 2 {
-		panic("bad polygon")
-	}
-	// iterate over list of points,
-	// assigning each pair to an edge,
-	// ending with the starting point.
-	for i := range p.points {
-		p.edges = append(p.edges, []Point{p.points[i], p.points[(i+1)%len(p.points)]})
-	}
+// Edges returns the line segments between each point and the next, and from the last back to the first.
+// a polygon with fewer than 2 points has none. they're the polygon's own, don't change them.
+func (p *Polygon) Edges() []Edge {
 	return p.edges
 }
 
-// Contains tells us whether a test point q is within an arbitrary polygon p
+// EPSILON is how far, relative to the length of an edge, a point can be from it and still be on it
+const EPSILON = 1e-9
+
+// Contains tells us whether a test point q is within an arbitrary polygon p.
+// points on an edge or a vertex count as inside, so touching a wall is hitting it.
+// a polygon with fewer than 3 points, or with all of them on a line, has no inside, only its edges.
 func (p *Polygon) Contains(q Point) bool {
-	// note: we can speed this up by doing a rough bounding box check and discarding points obviously outside a rectangular bounding box.
-	return p.BoundingBoxOverlaps(q.X, q.Y, q.X, q.Y)
+	switch len(p.points) {
+	case 0:
+		return false
+	case 1:
+		return q == p.points[0]
+	}
+	// rough bounding box check, discarding points obviously outside. the box includes its edges
+	x1, y1, x2, y2 := p.BoundingBox()
+	if q.X < x1 || q.X > x2 || q.Y < y1 || q.Y > y2 {
+		return false
+	}
 
 	// begin by assuming q is outside
 	isInside := false
 
 	for _, e := range p.Edges() {
-		a, b := e[0], e[1]
-		// make sure point a is lower than point b
-		if a.Y > b.Y {
-			a, b = b, a
+		a, b := e.A, e.B
+		if onSegment(q, a, b) {
+			return true
 		}
-		// make sure test point is not exact height of edge
-		if q.Y == a.Y || q.Y == b.Y {
-			q.Y += 0.000001
-		}
-
-		// check whether a horizontal ray would intersect
-		if q.Y > b.Y || q.Y < a.Y || q.X > math.Max(a.X, b.X) {
-			continue // does not intersect
+		// does a horizontal ray from q to the right cross this edge? an edge counts if one end is above q and the
+		// other is not, so a ray through a vertex is counted once for the two edges meeting there, or not at all
+		// if both go the same way. horizontal edges never count.
+		if (a.Y > q.Y) == (b.Y > q.Y) {
+			continue
 		}
-
-		if q.X < math.Min(a.X, b.X) {
+		// where the edge crosses the ray's height
+		x := a.X + (q.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+		if q.X < x {
 			// note: this algorithm depends on detecting
 			// whether a ray passing through the polygon
 			// has intersected an even or an odd number of
 			// sides. so we just flip our latest result
 			// every time we cast a ray through a side.
-			//
-			// in this case, does intersect, so invert current value
-			isInside = !isInside
-			continue
-		}
-
-		edgeN := b.Y - a.Y
-		edgeD := b.X - a.X
-		edgeSlope := edgeN / edgeD
-		if edgeD == 0 && edgeN != 0 {
-			edgeSlope = math.Inf(1)
-		}
-		pointN := q.Y - a.Y
-		pointD := q.X - a.X
-		pointSlope := pointN / pointD
-		if pointD == 0 && pointN != 0 {
-			pointSlope = math.Inf(1)
-		}
-
-		if pointSlope >= edgeSlope {
-			// does intersect, so invert current value
 			isInside = !isInside
-			continue
 		}
 	}
 
@@ -137,3 +159,15 @@ func (p *Polygon) Contains(q Point) bool {
 	// we're inside if we've intersected an odd number of edges.
 	return isInside
 }
+
+// onSegment tells us whether q is on the line segment from a to b
+func onSegment(q, a, b Point) bool {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	// distance from the line through a and b, times its length
+	cross := dx*(q.Y-a.Y) - dy*(q.X-a.X)
+	if math.Abs(cross) > EPSILON*math.Max(dx*dx+dy*dy, 1) {
+		return false
+	}
+	return q.X >= math.Min(a.X, b.X)-EPSILON && q.X <= math.Max(a.X, b.X)+EPSILON &&
+		q.Y >= math.Min(a.Y, b.Y)-EPSILON && q.Y <= math.Max(a.Y, b.Y)+EPSILON
+}
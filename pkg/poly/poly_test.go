@@ -0,0 +1,68 @@
+package poly
+
+import "testing"
+
+func TestContains(t *testing.T) {
+	square := MustNew(Point{0, 0}, Point{0, 10}, Point{10, 10}, Point{10, 0})
+	// a U opening upwards, the notch is 4 to 6 wide and goes down to y = 5
+	u := MustNew(Point{0, 0}, Point{0, 10}, Point{4, 10}, Point{4, 5}, Point{6, 5}, Point{6, 10}, Point{10, 10}, Point{10, 0})
+	// a star-ish arrow with a vertex pointing at the ray from the left
+	arrow := MustNew(Point{0, 0}, Point{5, 5}, Point{0, 10}, Point{10, 5})
+	// extra points along the edges
+	collinear := MustNew(Point{0, 0}, Point{0, 5}, Point{0, 10}, Point{5, 10}, Point{10, 10}, Point{10, 0}, Point{5, 0})
+	triangle := MustNew(Point{0, 0}, Point{5, 10}, Point{10, 0})
+
+	type test struct {
+		name string
+		p    *Polygon
+		q    Point
+		want bool
+	}
+	tests := []test{
+		{name: "square inside", p: square, q: Point{5, 5}, want: true},
+		{name: "square outside, inside its bounding box row", p: square, q: Point{11, 5}, want: false},
+		{name: "square outside", p: square, q: Point{-1, -1}, want: false},
+		{name: "square on edge", p: square, q: Point{10, 5}, want: true},
+		{name: "square on bottom edge", p: square, q: Point{5, 0}, want: true},
+		{name: "square on vertex", p: square, q: Point{0, 10}, want: true},
+		{name: "square at vertex height", p: square, q: Point{5, 10}, want: true},
+		{name: "square just outside", p: square, q: Point{10.001, 5}, want: false},
+
+		// concave: inside the bounding box, outside the shape
+		{name: "u notch", p: u, q: Point{5, 8}, want: false},
+		{name: "u notch floor", p: u, q: Point{5, 5}, want: true},
+		{name: "u below notch", p: u, q: Point{5, 4}, want: true},
+		{name: "u arm", p: u, q: Point{2, 8}, want: true},
+		{name: "u ray along notch floor", p: u, q: Point{1, 5}, want: true},
+		{name: "u ray through arm tops", p: u, q: Point{-1, 10}, want: false},
+		{name: "arrow notch", p: arrow, q: Point{2, 5}, want: false},
+		{name: "arrow tip of notch", p: arrow, q: Point{5, 5}, want: true},
+		{name: "arrow past notch", p: arrow, q: Point{7, 5}, want: true},
+		{name: "arrow ray through two vertices", p: arrow, q: Point{-1, 5}, want: false},
+
+		// collinear points along the edges don't count twice
+		{name: "collinear inside", p: collinear, q: Point{5, 5}, want: true},
+		{name: "collinear ray through middle vertex", p: collinear, q: Point{-1, 5}, want: false},
+		{name: "collinear at middle vertex height", p: collinear, q: Point{3, 5}, want: true},
+		{name: "collinear on middle vertex", p: collinear, q: Point{5, 10}, want: true},
+
+		{name: "triangle apex height", p: triangle, q: Point{2, 10}, want: false},
+		{name: "triangle just inside apex", p: triangle, q: Point{5, 9.9}, want: true},
+		{name: "triangle base", p: triangle, q: Point{5, 0}, want: true},
+
+		// degenerate: no inside, only edges. New won't make these, scaling by 0 or shrinking too far can
+		{name: "empty", p: build(), q: Point{0, 0}, want: false},
+		{name: "single point", p: build(Point{1, 1}), q: Point{1, 1}, want: true},
+		{name: "single point, elsewhere", p: build(Point{1, 1}), q: Point{1, 2}, want: false},
+		{name: "segment", p: build(Point{0, 0}, Point{10, 10}), q: Point{5, 5}, want: true},
+		{name: "segment, off it", p: build(Point{0, 0}, Point{10, 10}), q: Point{5, 6}, want: false},
+		{name: "flat", p: build(Point{0, 0}, Point{5, 0}, Point{10, 0}), q: Point{7, 0}, want: true},
+		{name: "flat, above", p: build(Point{0, 0}, Point{5, 0}, Point{10, 0}), q: Point{7, 1}, want: false},
+		{name: "repeated points", p: build(Point{0, 0}, Point{0, 0}, Point{0, 10}, Point{10, 10}, Point{10, 0}), q: Point{5, 5}, want: true},
+	}
+	for _, test := range tests {
+		if got := test.p.Contains(test.q); got != test.want {
+			t.Fatalf("%s: Contains(%v), expected: %t, got: %t", test.name, test.q, test.want, got)
+		}
+	}
+}
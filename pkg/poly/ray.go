@@ -0,0 +1,153 @@
+package poly
+
+// ray casting against edges, for range sensors: where does a ray first hit a shape, and which way is the surface facing.
+
+import (
+	"math"
+)
+
+// Ray starts at Origin and goes in the direction of Dir, forever. Dir doesn't need to be of unit length.
+type Ray struct {
+	Origin Point
+	Dir    Point
+}
+
+// Hit is where a ray meets a shape
+type Hit struct {
+	Distance float64 // from the ray's origin, in the same unit as the points
+	Point    Point
+	Normal   Point // unit length, perpendicular to the surface hit, facing back towards the ray's origin
+}
+
+// unit returns the ray's direction with a length of 1, and false if it has no direction
+func (r Ray) unit() (Point, bool) {
+	l := math.Hypot(r.Dir.X, r.Dir.Y)
+	if l == 0 {
+		return Point{}, false
+	}
+	return Point{X: r.Dir.X / l, Y: r.Dir.Y / l}, true
+}
+
+// IntersectSegment returns where the ray crosses the line segment from a to b.
+// a ray running along the segment hits it at the end closest to the origin. neither side of the segment faces it
+// then, the normal is the one on the right of a to b, which faces out of a counterclockwise ring.
+func (r Ray) IntersectSegment(a, b Point) (Hit, bool) {
+	return r.intersectSegment(a, b, 1)
+}
+
+// intersectSegment is IntersectSegment for an edge of a ring, with the normal of a ray running along it on the given
+// side, 1 for right and -1 for left, whichever faces out of the ring
+func (r Ray) intersectSegment(a, b Point, side float64) (Hit, bool) {
+	d, ok := r.unit()
+	if !ok {
+		return Hit{}, false
+	}
+	e := Point{X: b.X - a.X, Y: b.Y - a.Y}
+	ao := Point{X: a.X - r.Origin.X, Y: a.Y - r.Origin.Y}
+	denom := cross(d, e)
+	if math.Abs(denom) <= EPSILON*math.Max(math.Hypot(e.X, e.Y), 1) {
+		// parallel. if the segment is on the ray, its closest end in front of the origin is hit
+		if math.Abs(cross(ao, d)) > EPSILON*math.Max(math.Hypot(ao.X, ao.Y), 1) {
+			return Hit{}, false
+		}
+		ta, tb := dot(ao, d), dot(Point{X: b.X - r.Origin.X, Y: b.Y - r.Origin.Y}, d)
+		if ta < 0 && tb < 0 {
+			return Hit{}, false
+		}
+		// 0 if the origin is on the segment
+		t := math.Max(math.Min(ta, tb), 0)
+		n, ok := edgeNormal(a, b, side)
+		if !ok {
+			n = Point{X: -d.X, Y: -d.Y}
+		}
+		return Hit{Distance: t, Point: Point{X: r.Origin.X + t*d.X, Y: r.Origin.Y + t*d.Y}, Normal: n}, true
+	}
+	// origin + t*d = a + s*e
+	t := cross(ao, e) / denom
+	s := cross(ao, d) / denom
+	if t < 0 || s < -EPSILON || s > 1+EPSILON {
+		return Hit{}, false
+	}
+	return Hit{Distance: t, Point: Point{X: r.Origin.X + t*d.X, Y: r.Origin.Y + t*d.Y}, Normal: normal(e, d)}, true
+}
+
+// Raycast returns the closest place the ray hits the polygon's edges within max distance.
+// a ray starting inside the polygon hits it right away, at its origin. there's no surface there, the normal points
+// back along the ray.
+func (p *Polygon) Raycast(r Ray, max float64) (Hit, bool) {
+	d, ok := r.unit()
+	if !ok || len(p.points) == 0 {
+		return Hit{}, false
+	}
+	if !p.rayHitsBoundingBox(r.Origin, d, max) {
+		return Hit{}, false
+	}
+	if len(p.points) >= 3 && p.Contains(r.Origin) {
+		return Hit{Distance: 0, Point: r.Origin, Normal: Point{X: -d.X, Y: -d.Y}}, true
+	}
+	// with Y up, outward is to the right of the edges going counterclockwise, to the left going clockwise
+	side := 1.0
+	if p.Area() < 0 {
+		side = -1.0
+	}
+	var best Hit
+	found := false
+	for _, e := range p.Edges() {
+		h, ok := r.intersectSegment(e.A, e.B, side)
+		if ok && h.Distance <= max && (!found || closer(h, best, d)) {
+			best, found = h, true
+		}
+	}
+	return best, found
+}
+
+// closer is true if h is a better hit than best for a ray in direction d: closer, or as close and on an edge the ray
+// comes at rather than runs along, like the next edge at the corner where a ray along a wall ends
+func closer(h, best Hit, d Point) bool {
+	if math.Abs(h.Distance-best.Distance) <= EPSILON {
+		return math.Abs(dot(h.Normal, d)) > math.Abs(dot(best.Normal, d))
+	}
+	return h.Distance < best.Distance
+}
+
+// rayHitsBoundingBox is the slab test: does the ray from o in unit direction d get into the bounding box within max
+func (p *Polygon) rayHitsBoundingBox(o, d Point, max float64) bool {
+	x1, y1, x2, y2 := p.BoundingBox()
+	tmin, tmax := 0.0, max
+	for _, slab := range [][4]float64{{o.X, d.X, x1, x2}, {o.Y, d.Y, y1, y2}} {
+		o, d, lo, hi := slab[0], slab[1], slab[2], slab[3]
+		if d == 0 {
+			if o < lo || o > hi {
+				return false
+			}
+			continue
+		}
+		t1, t2 := (lo-o)/d, (hi-o)/d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tmin, tmax = math.Max(tmin, t1), math.Min(tmax, t2)
+		if tmin > tmax+EPSILON {
+			return false
+		}
+	}
+	return true
+}
+
+// normal returns the unit normal of an edge e on the side a ray in direction d comes from
+func normal(e, d Point) Point {
+	l := math.Hypot(e.X, e.Y)
+	n := Point{X: -e.Y / l, Y: e.X / l}
+	if dot(n, d) > 0 {
+		n = Point{X: -n.X, Y: -n.Y}
+	}
+	return n
+}
+
+func cross(a, b Point) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+func dot(a, b Point) float64 {
+	return a.X*b.X + a.Y*b.Y
+}
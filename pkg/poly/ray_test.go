@@ -0,0 +1,131 @@
+package poly
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntersectSegment(t *testing.T) {
+	type test struct {
+		name string
+		ray  Ray
+		a, b Point
+		hit  bool
+		want Hit
+	}
+	tests := []test{
+		{name: "straight on", ray: Ray{Point{0, 0}, Point{1, 0}}, a: Point{5, -1}, b: Point{5, 1}, hit: true, want: Hit{5, Point{5, 0}, Point{-1, 0}}},
+		// the length of the direction doesn't matter
+		{name: "long direction", ray: Ray{Point{0, 0}, Point{10, 0}}, a: Point{5, -1}, b: Point{5, 1}, hit: true, want: Hit{5, Point{5, 0}, Point{-1, 0}}},
+		{name: "from the other side", ray: Ray{Point{10, 0}, Point{-1, 0}}, a: Point{5, -1}, b: Point{5, 1}, hit: true, want: Hit{5, Point{5, 0}, Point{1, 0}}},
+		{name: "diagonal", ray: Ray{Point{0, 0}, Point{1, 1}}, a: Point{0, 4}, b: Point{4, 0}, hit: true, want: Hit{2 * math.Sqrt2, Point{2, 2}, Point{-math.Sqrt2 / 2, -math.Sqrt2 / 2}}},
+		{name: "end point", ray: Ray{Point{0, 0}, Point{1, 0}}, a: Point{5, 0}, b: Point{5, 1}, hit: true, want: Hit{5, Point{5, 0}, Point{-1, 0}}},
+		{name: "behind", ray: Ray{Point{0, 0}, Point{-1, 0}}, a: Point{5, -1}, b: Point{5, 1}},
+		{name: "beside", ray: Ray{Point{0, 0}, Point{1, 0}}, a: Point{5, 1}, b: Point{5, 2}},
+		{name: "parallel", ray: Ray{Point{0, 0}, Point{1, 0}}, a: Point{0, 1}, b: Point{5, 1}},
+		{name: "along", ray: Ray{Point{0, 0}, Point{1, 0}}, a: Point{7, 0}, b: Point{3, 0}, hit: true, want: Hit{3, Point{3, 0}, Point{0, 1}}},
+		{name: "along, from on it", ray: Ray{Point{4, 0}, Point{1, 0}}, a: Point{7, 0}, b: Point{3, 0}, hit: true, want: Hit{0, Point{4, 0}, Point{0, 1}}},
+		{name: "along, behind", ray: Ray{Point{8, 0}, Point{1, 0}}, a: Point{7, 0}, b: Point{3, 0}},
+		{name: "no direction", ray: Ray{Point{0, 0}, Point{0, 0}}, a: Point{5, -1}, b: Point{5, 1}},
+	}
+	for _, test := range tests {
+		got, hit := test.ray.IntersectSegment(test.a, test.b)
+		if hit != test.hit || hit && !hitEq(got, test.want) {
+			t.Fatalf("%s: IntersectSegment(%v, %v), expected: %t %+v, got: %t %+v", test.name, test.a, test.b, test.hit, test.want, hit, got)
+		}
+	}
+}
+
+func TestRaycast(t *testing.T) {
+	square := MustNew(Point{0, 0}, Point{0, 10}, Point{10, 10}, Point{10, 0})
+	// thin walls used to be stepped over
+	thin := MustNew(Point{5, -5}, Point{5, 5}, Point{5.01, 5}, Point{5.01, -5})
+	wedge := MustNew(Point{0, 0}, Point{4, 0}, Point{-2, 4})
+	u := MustNew(Point{0, 0}, Point{0, 10}, Point{4, 10}, Point{4, 5}, Point{6, 5}, Point{6, 10}, Point{10, 10}, Point{10, 0})
+
+	type test struct {
+		name string
+		p    *Polygon
+		ray  Ray
+		max  float64
+		hit  bool
+		want Hit
+	}
+	tests := []test{
+		{name: "nearest edge", p: square, ray: Ray{Point{-5, 5}, Point{1, 0}}, max: 100, hit: true, want: Hit{5, Point{0, 5}, Point{-1, 0}}},
+		{name: "too far", p: square, ray: Ray{Point{-5, 5}, Point{1, 0}}, max: 4},
+		{name: "missing the bounding box", p: square, ray: Ray{Point{-5, 5}, Point{0, 1}}, max: 100},
+		{name: "inside", p: square, ray: Ray{Point{5, 5}, Point{1, 0}}, max: 100, hit: true, want: Hit{0, Point{5, 5}, Point{-1, 0}}},
+		{name: "thin wall", p: thin, ray: Ray{Point{0, 0}, Point{1, 0}}, max: 100, hit: true, want: Hit{5, Point{5, 0}, Point{-1, 0}}},
+		// down into the notch of the U, its floor is hit rather than the arms' tops
+		{name: "concave", p: u, ray: Ray{Point{5, 20}, Point{0, -1}}, max: 100, hit: true, want: Hit{15, Point{5, 5}, Point{0, 1}}},
+		{name: "concave, past the arm", p: u, ray: Ray{Point{-5, 8}, Point{1, 0}}, max: 100, hit: true, want: Hit{5, Point{0, 8}, Point{-1, 0}}},
+		// along the bottom edge to the corner, where the slanted edge is what's in the way
+		{name: "along an edge", p: wedge, ray: Ray{Point{-5, 0}, Point{1, 0}}, max: 100, hit: true, want: Hit{5, Point{0, 0}, Point{-2 / math.Sqrt(5), -1 / math.Sqrt(5)}}},
+		{name: "corner", p: square, ray: Ray{Point{-1, -1}, Point{1, 1}}, max: 100, hit: true, want: Hit{math.Sqrt2, Point{0, 0}, Point{-1, 0}}},
+	}
+	for _, test := range tests {
+		got, hit := test.p.Raycast(test.ray, test.max)
+		if hit != test.hit || hit && !hitEq(got, test.want) {
+			t.Fatalf("%s: Raycast(%+v, %.1f), expected: %t %+v, got: %t %+v", test.name, test.ray, test.max, test.hit, test.want, hit, got)
+		}
+	}
+}
+
+// a ray running along an edge gets the normal facing out of what the ring bounds, whichever way the ring goes
+func TestRayAlongEdge(t *testing.T) {
+	cw := MustNew(Point{0, 0}, Point{0, 10}, Point{10, 10}, Point{10, 0})
+	ccw := cw.Oriented(COUNTERCLOCKWISE)
+	// an L shaped hole, with a corner poking into the room
+	walls := MustNewShape(rect(0, 0, 10, 10), MustNew(Point{1, 1}, Point{9, 1}, Point{9, 5}, Point{5, 5}, Point{5, 9}, Point{1, 9}))
+
+	type test struct {
+		name        string
+		rings       []*Polygon
+		orientation Orientation
+		side        float64
+		outside     func(Point) bool
+	}
+	tests := []test{
+		{name: "clockwise", rings: []*Polygon{cw}, orientation: CLOCKWISE, side: -1, outside: func(q Point) bool { return !cw.Contains(q) }},
+		{name: "counterclockwise", rings: []*Polygon{ccw}, orientation: COUNTERCLOCKWISE, side: 1, outside: func(q Point) bool { return !ccw.Contains(q) }},
+		{name: "hole", rings: walls.Holes(), orientation: CLOCKWISE, side: 1, outside: func(q Point) bool { return !walls.Contains(q) }},
+	}
+	for _, test := range tests {
+		for _, ring := range test.rings {
+			if ring.Orientation() != test.orientation {
+				t.Fatalf("%s: expected a %v ring, got: %v", test.name, test.orientation, ring.Orientation())
+			}
+			for _, e := range ring.Edges() {
+				// from a bit before the edge, along it
+				d := Point{X: e.B.X - e.A.X, Y: e.B.Y - e.A.Y}
+				ray := Ray{Origin: Point{X: e.A.X - 0.1*d.X, Y: e.A.Y - 0.1*d.Y}, Dir: d}
+				h, ok := ray.intersectSegment(e.A, e.B, test.side)
+				if !ok || !pointEq(h.Point, e.A) {
+					t.Fatalf("%s: intersectSegment(%v, %v), expected a hit at %v, got: %v %+v", test.name, e.A, e.B, e.A, ok, h)
+				}
+				mid := Point{X: (e.A.X+e.B.X)/2 + 0.1*h.Normal.X, Y: (e.A.Y+e.B.Y)/2 + 0.1*h.Normal.Y}
+				if !test.outside(mid) {
+					t.Fatalf("%s: intersectSegment(%v, %v), expected the normal to face out, got: %v", test.name, e.A, e.B, h.Normal)
+				}
+			}
+		}
+	}
+
+	// and raycasting picks the side from the ring, a clockwise one gets the same hits as a counterclockwise one
+	for _, ray := range []Ray{{Point{-5, 0}, Point{1, 0}}, {Point{5, -5}, Point{0, 1}}, {Point{-1, -1}, Point{1, 1}}} {
+		want, wantOk := ccw.Raycast(ray, 100)
+		got, ok := cw.Raycast(ray, 100)
+		if ok != wantOk || !hitEq(got, want) {
+			t.Fatalf("Raycast(%v), expected: %v %+v, got: %v %+v", ray, wantOk, want, ok, got)
+		}
+	}
+}
+
+// hitEq compares hits, give or take rounding
+func hitEq(a, b Hit) bool {
+	const e = 1e-9
+	return math.Abs(a.Distance-b.Distance) < e &&
+		math.Abs(a.Point.X-b.Point.X) < e && math.Abs(a.Point.Y-b.Point.Y) < e &&
+		math.Abs(a.Normal.X-b.Normal.X) < e && math.Abs(a.Normal.Y-b.Normal.Y) < e
+}
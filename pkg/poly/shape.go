@@ -0,0 +1,367 @@
+package poly
+
+// shapes with holes in them, like a room's walls around its floor, and groups of shapes that belong together,
+// like the two walls of a corridor. a Polygon is a single ring of points, a Shape is an outer ring with rings
+// cut out of it, and a MultiShape is any number of Shapes.
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrHoleOutside  = errors.New("poly: hole is not inside the outer ring")
+	ErrHolesOverlap = errors.New("poly: holes overlap")
+)
+
+// Shape is the area inside an outer ring and outside all of its holes. the edges of the holes are part of it,
+// so a chair in a room touching the wall is touching the shape.
+type Shape struct {
+	outer *Polygon // counterclockwise
+	holes []*Polygon
+}
+
+// NewShape makes a shape from an outer ring and holes, which must be inside it and not touch it or each other.
+// the rings can go either way, the outer one ends up counterclockwise and the holes clockwise.
+func NewShape(outer *Polygon, holes ...*Polygon) (*Shape, error) {
+	s := &Shape{outer: outer.Oriented(COUNTERCLOCKWISE)}
+	for i, h := range holes {
+		if len(h.points) == 0 || ringsTouch(h, outer) || !outer.Contains(h.points[0]) {
+			return nil, ErrHoleOutside
+		}
+		for _, o := range holes[:i] {
+			if ringsTouch(h, o) || o.Contains(h.points[0]) || h.Contains(o.points[0]) {
+				return nil, ErrHolesOverlap
+			}
+		}
+		s.holes = append(s.holes, h.Oriented(CLOCKWISE))
+	}
+	return s, nil
+}
+
+// MustNewShape is NewShape for shapes written out in code, it panics if they're wrong
+func MustNewShape(outer *Polygon, holes ...*Polygon) *Shape {
+	s, err := NewShape(outer, holes...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ringsTouch is true if any edge of a crosses or touches any edge of b
+func ringsTouch(a, b *Polygon) bool {
+	if !a.boundingBoxTouches(b) {
+		return false
+	}
+	for _, e := range a.Edges() {
+		for _, f := range b.Edges() {
+			if segmentsIntersect(e.A, e.B, f.A, f.B) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// boundingBoxTouches is BoundingBoxOverlaps, counting boxes that only share an edge
+func (p *Polygon) boundingBoxTouches(q *Polygon) bool {
+	ax1, ay1, ax2, ay2 := p.BoundingBox()
+	bx1, by1, bx2, by2 := q.BoundingBox()
+	return ax1 <= bx2 && bx1 <= ax2 && ay1 <= by2 && by1 <= ay2
+}
+
+func (s *Shape) Outer() *Polygon {
+	return s.outer
+}
+
+func (s *Shape) Holes() []*Polygon {
+	return append([]*Polygon(nil), s.holes...)
+}
+
+// Rings returns the outer ring followed by the holes, for drawing the shape's edges
+func (s *Shape) Rings() []*Polygon {
+	return append([]*Polygon{s.outer}, s.holes...)
+}
+
+// BoundingBox is the outer ring's, the holes are inside it
+func (s *Shape) BoundingBox() (x1, y1, x2, y2 float64) {
+	return s.outer.BoundingBox()
+}
+
+// Area returns the outer ring's area less the holes', always positive
+func (s *Shape) Area() float64 {
+	a := math.Abs(s.outer.Area())
+	for _, h := range s.holes {
+		a -= math.Abs(h.Area())
+	}
+	return a
+}
+
+// Contains is true if q is inside the outer ring and not inside a hole, edges count as inside
+func (s *Shape) Contains(q Point) bool {
+	if !s.outer.Contains(q) {
+		return false
+	}
+	for _, h := range s.holes {
+		if h.Contains(q) && !h.onEdge(q) {
+			return false
+		}
+	}
+	return true
+}
+
+// onEdge is true if q is on one of the polygon's edges
+func (p *Polygon) onEdge(q Point) bool {
+	for _, e := range p.Edges() {
+		if onSegment(q, e.A, e.B) {
+			return true
+		}
+	}
+	return false
+}
+
+// Distance returns how far q is from the shape, 0 if it's in it
+func (s *Shape) Distance(q Point) float64 {
+	if s.Contains(q) {
+		return 0
+	}
+	best := math.Inf(1)
+	for _, r := range s.Rings() {
+		for _, e := range r.Edges() {
+			best = math.Min(best, segmentDistance(q, e.A, e.B))
+		}
+	}
+	return best
+}
+
+// Raycast returns the closest place the ray hits the edges of any ring within max distance. a ray starting
+// inside the shape hits it right away, with the normal pointing back along the ray, one starting in a hole hits
+// the hole's edges.
+func (s *Shape) Raycast(r Ray, max float64) (Hit, bool) {
+	d, ok := r.unit()
+	if !ok || len(s.outer.points) == 0 || !s.outer.rayHitsBoundingBox(r.Origin, d, max) {
+		return Hit{}, false
+	}
+	if s.Contains(r.Origin) {
+		return Hit{Distance: 0, Point: r.Origin, Normal: Point{X: -d.X, Y: -d.Y}}, true
+	}
+	var best Hit
+	found := false
+	// the outer ring goes counterclockwise and the holes clockwise, so the shape is on the left of every edge and
+	// the right faces out of it, into a hole for a hole's edges
+	for _, ring := range s.Rings() {
+		for _, e := range ring.Edges() {
+			h, ok := r.intersectSegment(e.A, e.B, 1)
+			if ok && h.Distance <= max && (!found || closer(h, best, d)) {
+				best, found = h, true
+			}
+		}
+	}
+	return best, found
+}
+
+// Offset returns the shape grown by d, or shrunk for a negative d: the outer ring grows and the holes shrink.
+// holes that shrink away to nothing are dropped. see Polygon.Offset for what can go wrong.
+func (s *Shape) Offset(d float64, join Join) *Shape {
+	out := &Shape{outer: s.outer.Offset(d, join)}
+	for _, h := range s.holes {
+		o := h.Offset(-d, join)
+		if d > 0 && collapsed(h, o, d) {
+			continue
+		}
+		out.holes = append(out.holes, o)
+	}
+	return out
+}
+
+// collapsed is true if a hole shrunk by d has closed up, in full or across part of it. shrunk past nothing the
+// offset edges cross over each other and come out turned around, closer to the old edges than d or outside them.
+func collapsed(hole, shrunk *Polygon, d float64) bool {
+	for _, pt := range shrunk.points {
+		if !hole.Contains(pt) {
+			return true
+		}
+		for _, e := range hole.Edges() {
+			if segmentDistance(pt, e.A, e.B) < d*(1-1e-6) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decompose splits the shape into convex polygons covering the same area. each hole is joined to the outer
+// ring by a cut, making a single ring going out along the cut, around the hole and back, which is then split
+// like any polygon.
+func (s *Shape) Decompose() []*Polygon {
+	if len(s.holes) == 0 {
+		return s.outer.Decompose()
+	}
+	ring := s.outer.Points()
+	// the rightmost hole first, so the cuts for the ones after it can go to it
+	holes := s.Holes()
+	for i := 1; i < len(holes); i++ {
+		for j := i; j > 0 && rightmost(holes[j]).X > rightmost(holes[j-1]).X; j-- {
+			holes[j], holes[j-1] = holes[j-1], holes[j]
+		}
+	}
+	for i, h := range holes {
+		ring = s.cut(ring, h, holes[i+1:])
+	}
+	return build(ring...).Decompose()
+}
+
+// rightmost returns the point furthest to the right
+func rightmost(p *Polygon) Point {
+	r := p.points[0]
+	for _, pt := range p.points {
+		if pt.X > r.X {
+			r = pt
+		}
+	}
+	return r
+}
+
+// cut joins a hole to the ring with a cut from the hole's rightmost point to the closest point on the ring it
+// can see, without crossing the ring, the hole or the holes still to be joined
+func (s *Shape) cut(ring []Point, h *Polygon, rest []*Polygon) []Point {
+	m := 0
+	for i, pt := range h.points {
+		if pt.X > h.points[m].X {
+			m = i
+		}
+	}
+	from := h.points[m]
+	best := -1
+	for i, to := range ring {
+		if best >= 0 && math.Hypot(to.X-from.X, to.Y-from.Y) >= math.Hypot(ring[best].X-from.X, ring[best].Y-from.Y) {
+			continue
+		}
+		if s.canCut(from, to, ring, append([]*Polygon{h}, rest...)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		// can't happen for a valid shape, there's always a way out of a hole
+		return ring
+	}
+	// around the ring to the cut, along it to the hole, around the hole, and back
+	out := append([]Point(nil), ring[:best+1]...)
+	out = append(out, h.points[m:]...)
+	out = append(out, h.points[:m+1]...)
+	return append(append(out, ring[best]), ring[best+1:]...)
+}
+
+// canCut is true if the segment from a to b only touches the rings at its ends and runs through the shape
+func (s *Shape) canCut(a, b Point, ring []Point, holes []*Polygon) bool {
+	if a == b {
+		return false
+	}
+	rings := append([]*Polygon{build(ring...)}, holes...)
+	for _, r := range rings {
+		for _, e := range r.Edges() {
+			if e.A == a || e.B == a || e.A == b || e.B == b {
+				// the edges at either end, the cut mustn't run along them
+				if onSegment(e.A, a, b) && e.A != a && e.A != b || onSegment(e.B, a, b) && e.B != a && e.B != b {
+					return false
+				}
+				continue
+			}
+			if segmentsIntersect(a, b, e.A, e.B) {
+				return false
+			}
+		}
+	}
+	// crossing nothing, the whole cut is on the same side of everything as its middle
+	mid := Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+	for _, r := range rings {
+		if r.onEdge(mid) {
+			return false
+		}
+	}
+	return s.Contains(mid)
+}
+
+// MultiShape is several shapes treated as one, they can be apart or one can be in another's hole
+type MultiShape []*Shape
+
+// BoundingBox returns the box around all the shapes, all 0 if there are none
+func (m MultiShape) BoundingBox() (x1, y1, x2, y2 float64) {
+	for i, s := range m {
+		sx1, sy1, sx2, sy2 := s.BoundingBox()
+		if i == 0 {
+			x1, y1, x2, y2 = sx1, sy1, sx2, sy2
+			continue
+		}
+		x1, y1, x2, y2 = math.Min(x1, sx1), math.Min(y1, sy1), math.Max(x2, sx2), math.Max(y2, sy2)
+	}
+	return x1, y1, x2, y2
+}
+
+// Area returns the total area of the shapes, which shouldn't overlap
+func (m MultiShape) Area() float64 {
+	a := 0.0
+	for _, s := range m {
+		a += s.Area()
+	}
+	return a
+}
+
+// Contains is true if q is in any of the shapes
+func (m MultiShape) Contains(q Point) bool {
+	for _, s := range m {
+		if s.Contains(q) {
+			return true
+		}
+	}
+	return false
+}
+
+// Distance returns how far q is from the closest shape
+func (m MultiShape) Distance(q Point) float64 {
+	best := math.Inf(1)
+	for _, s := range m {
+		best = math.Min(best, s.Distance(q))
+	}
+	return best
+}
+
+// Raycast returns the closest place the ray hits any of the shapes within max distance
+func (m MultiShape) Raycast(r Ray, max float64) (Hit, bool) {
+	var best Hit
+	found := false
+	for _, s := range m {
+		h, ok := s.Raycast(r, max)
+		if ok && (!found || h.Distance < best.Distance) {
+			best, found = h, true
+		}
+	}
+	return best, found
+}
+
+// Rings returns the rings of all the shapes
+func (m MultiShape) Rings() []*Polygon {
+	var out []*Polygon
+	for _, s := range m {
+		out = append(out, s.Rings()...)
+	}
+	return out
+}
+
+// Offset grows or shrinks each shape, see Shape.Offset. shapes grown into each other overlap.
+func (m MultiShape) Offset(d float64, join Join) MultiShape {
+	out := make(MultiShape, len(m))
+	for i, s := range m {
+		out[i] = s.Offset(d, join)
+	}
+	return out
+}
+
+// Decompose splits all the shapes into convex polygons
+func (m MultiShape) Decompose() []*Polygon {
+	var out []*Polygon
+	for _, s := range m {
+		out = append(out, s.Decompose()...)
+	}
+	return out
+}
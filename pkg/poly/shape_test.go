@@ -0,0 +1,170 @@
+package poly
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// room returns the walls of a room: a w by h rectangle at x, y with walls t thick
+func room(x, y, w, h, t float64) *Shape {
+	return MustNewShape(rect(x, y, w, h), rect(x+t, y+t, w-2*t, h-2*t))
+}
+
+func TestNewShape(t *testing.T) {
+	type test struct {
+		name  string
+		outer *Polygon
+		holes []*Polygon
+		err   error
+	}
+	tests := []test{
+		{name: "no holes", outer: rect(0, 0, 10, 10)},
+		{name: "two holes", outer: rect(0, 0, 10, 10), holes: []*Polygon{rect(1, 1, 2, 2), rect(5, 5, 2, 2)}},
+		{name: "hole outside", outer: rect(0, 0, 10, 10), holes: []*Polygon{rect(20, 1, 2, 2)}, err: ErrHoleOutside},
+		{name: "hole sticking out", outer: rect(0, 0, 10, 10), holes: []*Polygon{rect(9, 1, 2, 2)}, err: ErrHoleOutside},
+		// a doorway isn't a hole, the outer ring has to go around it
+		{name: "hole touching the outside", outer: rect(0, 0, 10, 10), holes: []*Polygon{rect(1, 0, 2, 2)}, err: ErrHoleOutside},
+		{name: "outer inside the hole", outer: rect(1, 1, 2, 2), holes: []*Polygon{rect(0, 0, 10, 10)}, err: ErrHoleOutside},
+		{name: "holes overlapping", outer: rect(0, 0, 10, 10), holes: []*Polygon{rect(1, 1, 3, 3), rect(2, 2, 3, 3)}, err: ErrHolesOverlap},
+		{name: "hole in a hole", outer: rect(0, 0, 10, 10), holes: []*Polygon{rect(1, 1, 6, 6), rect(2, 2, 1, 1)}, err: ErrHolesOverlap},
+	}
+	for _, test := range tests {
+		s, err := NewShape(test.outer, test.holes...)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%s: NewShape(), expected: %v, got: %v", test.name, test.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if s.Outer().Orientation() != COUNTERCLOCKWISE {
+			t.Fatalf("%s: NewShape(), expected: a counterclockwise outer ring, got: %v", test.name, s.Outer().Orientation())
+		}
+		for _, h := range s.Holes() {
+			if h.Orientation() != CLOCKWISE {
+				t.Fatalf("%s: NewShape(), expected: clockwise holes, got: %v", test.name, h.Orientation())
+			}
+		}
+	}
+}
+
+func TestShapeContains(t *testing.T) {
+	walls := room(0, 0, 10, 10, 1)
+	// a pillar standing in the room, apart from its walls
+	building := MultiShape{walls, MustNewShape(rect(4, 4, 2, 2))}
+
+	type test struct {
+		name string
+		s    Solid
+		q    Point
+		want bool
+	}
+	tests := []test{
+		{name: "in the wall", s: walls, q: Point{0.5, 5}, want: true},
+		{name: "on the inside of the wall", s: walls, q: Point{1, 5}, want: true},
+		{name: "on the outside of the wall", s: walls, q: Point{0, 5}, want: true},
+		{name: "in the room", s: walls, q: Point{5, 5}, want: false},
+		{name: "outside", s: walls, q: Point{11, 5}, want: false},
+		{name: "pillar", s: building, q: Point{5, 5}, want: true},
+		{name: "between the pillar and the wall", s: building, q: Point{2, 5}, want: false},
+		{name: "wall, with the pillar", s: building, q: Point{9.5, 5}, want: true},
+	}
+	for _, test := range tests {
+		if got := test.s.Contains(test.q); got != test.want {
+			t.Fatalf("%s: Contains(%v), expected: %t, got: %t", test.name, test.q, test.want, got)
+		}
+	}
+
+	if got := walls.Area(); got != 36 {
+		t.Fatalf("Area(), expected: 36, got: %v", got)
+	}
+	if x1, y1, x2, y2 := building.BoundingBox(); x1 != 0 || y1 != 0 || x2 != 10 || y2 != 10 {
+		t.Fatalf("BoundingBox(), expected: 0 0 10 10, got: %v %v %v %v", x1, y1, x2, y2)
+	}
+}
+
+func TestShapeRaycast(t *testing.T) {
+	building := MultiShape{room(0, 0, 10, 10, 1), MustNewShape(rect(4, 4, 2, 2))}
+
+	type test struct {
+		name   string
+		ray    Ray
+		max    float64
+		hit    bool
+		dist   float64
+		normal Point
+	}
+	tests := []test{
+		// from inside the room, the walls are all around
+		{name: "to the east wall", ray: Ray{Origin: Point{2, 2}, Dir: Point{1, 0}}, max: 20, hit: true, dist: 7, normal: Point{-1, 0}},
+		{name: "to the pillar", ray: Ray{Origin: Point{2, 5}, Dir: Point{1, 0}}, max: 20, hit: true, dist: 2, normal: Point{-1, 0}},
+		{name: "short of the wall", ray: Ray{Origin: Point{2, 2}, Dir: Point{0, -1}}, max: 0.5},
+		{name: "to the south wall", ray: Ray{Origin: Point{2, 2}, Dir: Point{0, -1}}, max: 20, hit: true, dist: 1, normal: Point{0, 1}},
+		{name: "from outside", ray: Ray{Origin: Point{-5, 5}, Dir: Point{1, 0}}, max: 20, hit: true, dist: 5, normal: Point{-1, 0}},
+		{name: "in the wall", ray: Ray{Origin: Point{0.5, 5}, Dir: Point{1, 0}}, max: 20, hit: true, dist: 0, normal: Point{-1, 0}},
+		{name: "away from everything", ray: Ray{Origin: Point{-5, 5}, Dir: Point{-1, 0}}, max: 20},
+	}
+	for _, test := range tests {
+		h, ok := building.Raycast(test.ray, test.max)
+		if ok != test.hit || ok && (math.Abs(h.Distance-test.dist) > EPSILON || !pointEq(h.Normal, test.normal)) {
+			t.Fatalf("%s: Raycast(%v), expected: %v %v %v, got: %v %v", test.name, test.ray, test.hit, test.dist, test.normal, ok, h)
+		}
+	}
+
+	if d := building.Distance(Point{2, 5}); math.Abs(d-1) > EPSILON {
+		t.Fatalf("Distance(), expected: 1, got: %v", d)
+	}
+}
+
+func TestShapeDecompose(t *testing.T) {
+	type test struct {
+		name string
+		s    *Shape
+	}
+	tests := []test{
+		{name: "no holes", s: MustNewShape(rect(0, 0, 4, 4))},
+		{name: "room", s: room(0, 0, 10, 10, 1)},
+		{name: "two holes", s: MustNewShape(rect(0, 0, 10, 10), rect(1, 1, 2, 2), rect(6, 5, 2, 4))},
+		// the second hole's cut can't go straight right, the first one's in the way
+		{name: "holes in a row", s: MustNewShape(rect(0, 0, 10, 4), rect(1, 1, 2, 2), rect(5, 1, 2, 2))},
+		{name: "triangle hole", s: MustNewShape(rect(0, 0, 10, 10), MustNew(Point{3, 3}, Point{7, 3}, Point{5, 7}))},
+	}
+	for _, test := range tests {
+		pieces := test.s.Decompose()
+		area := 0.0
+		for _, p := range pieces {
+			if !p.IsConvex() {
+				t.Fatalf("%s: Decompose(), expected: convex pieces, got: %v", test.name, p.points)
+			}
+			// nothing covers a hole
+			for _, h := range test.s.Holes() {
+				if c := p.Centroid(); h.Contains(c) {
+					t.Fatalf("%s: Decompose(), expected: nothing in the holes, got: %v", test.name, p.points)
+				}
+			}
+			area += math.Abs(p.Area())
+		}
+		if math.Abs(area-test.s.Area()) > 1e-6 {
+			t.Fatalf("%s: Decompose(), expected: an area of %v, got: %v", test.name, test.s.Area(), area)
+		}
+	}
+}
+
+func TestShapeOffset(t *testing.T) {
+	walls := room(0, 0, 10, 10, 1)
+
+	grown := walls.Offset(0.5, JOIN_MITER)
+	if got := grown.Area(); math.Abs(got-(11*11-7*7)) > 1e-6 {
+		t.Fatalf("Offset(0.5), expected: an area of %v, got: %v", 11*11-7*7, got)
+	}
+	// the room is 8 across, growing the walls by more than 4 fills it
+	filled := walls.Offset(4.5, JOIN_MITER)
+	if len(filled.Holes()) != 0 {
+		t.Fatalf("Offset(4.5), expected: no holes, got: %v", filled.Holes())
+	}
+	// a long narrow hole closes up across its width before its ends meet
+	slot := MustNewShape(rect(0, 0, 10, 4), rect(1, 1, 8, 2)).Offset(1.5, JOIN_ROUND)
+	if len(slot.Holes()) != 0 {
+		t.Fatalf("Offset(1.5), expected: no holes, got: %v", slot.Holes()[0].points)
+	}
+}
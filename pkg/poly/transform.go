@@ -0,0 +1,114 @@
+package poly
+
+// moving shapes around. a Transform is any affine map, a Pose is the rigid kind that only moves and turns,
+// like a wheelchair driving around. polygons don't change, transforming one makes a new one with its own
+// bounding box and edges.
+
+import (
+	"math"
+)
+
+// Transform maps a point (x, y) to (A*x + B*y + E, C*x + D*y + F)
+type Transform struct {
+	A, B, C, D, E, F float64
+}
+
+// Identity leaves points where they are
+func Identity() Transform {
+	return Transform{A: 1, D: 1}
+}
+
+func Translate(dx, dy float64) Transform {
+	return Transform{A: 1, D: 1, E: dx, F: dy}
+}
+
+// Rotate turns points counterclockwise around the origin, with Y up
+func Rotate(rad float64) Transform {
+	s, c := math.Sincos(rad)
+	return Transform{A: c, B: -s, C: s, D: c}
+}
+
+// Scale stretches points away from the origin
+func Scale(sx, sy float64) Transform {
+	return Transform{A: sx, D: sy}
+}
+
+// Apply returns where the transform puts p
+func (t Transform) Apply(p Point) Point {
+	return Point{X: t.A*p.X + t.B*p.Y + t.E, Y: t.C*p.X + t.D*p.Y + t.F}
+}
+
+// Compose returns the transform that applies u first, then t
+func (t Transform) Compose(u Transform) Transform {
+	return Transform{
+		A: t.A*u.A + t.B*u.C,
+		B: t.A*u.B + t.B*u.D,
+		C: t.C*u.A + t.D*u.C,
+		D: t.C*u.B + t.D*u.D,
+		E: t.A*u.E + t.B*u.F + t.E,
+		F: t.C*u.E + t.D*u.F + t.F,
+	}
+}
+
+// Then returns the transform that applies t first, then u
+func (t Transform) Then(u Transform) Transform {
+	return u.Compose(t)
+}
+
+// Invert returns the transform that undoes t, false if there is none because t flattens everything onto a line
+func (t Transform) Invert() (Transform, bool) {
+	det := t.A*t.D - t.B*t.C
+	if math.Abs(det) <= EPSILON {
+		return Transform{}, false
+	}
+	a, b, c, d := t.D/det, -t.B/det, -t.C/det, t.A/det
+	return Transform{A: a, B: b, C: c, D: d, E: -(a*t.E + b*t.F), F: -(c*t.E + d*t.F)}, true
+}
+
+// Pose is where something is and which way it's turned: its own frame is turned counterclockwise by Angle
+// radians and moved to X, Y
+type Pose struct {
+	X, Y  float64
+	Angle float64 // radians, counterclockwise with Y up
+}
+
+// Transform maps points in the pose's own frame to the frame the pose is in
+func (p Pose) Transform() Transform {
+	return Translate(p.X, p.Y).Compose(Rotate(p.Angle))
+}
+
+// Apply maps a point in the pose's own frame to the frame the pose is in
+func (p Pose) Apply(q Point) Point {
+	return p.Transform().Apply(q)
+}
+
+// Compose returns q, a pose in p's own frame, in the frame p is in
+func (p Pose) Compose(q Pose) Pose {
+	at := p.Apply(Point{X: q.X, Y: q.Y})
+	return Pose{X: at.X, Y: at.Y, Angle: p.Angle + q.Angle}
+}
+
+// Invert returns the pose of p's parent frame as seen from p, so p.Compose(p.Invert()) is the identity
+func (p Pose) Invert() Pose {
+	s, c := math.Sincos(p.Angle)
+	return Pose{X: -(c*p.X + s*p.Y), Y: -(-s*p.X + c*p.Y), Angle: -p.Angle}
+}
+
+// Points returns a copy of the polygon's points
+func (p *Polygon) Points() []Point {
+	return append([]Point(nil), p.points...)
+}
+
+// Transform returns a new polygon with every point transformed
+func (p *Polygon) Transform(t Transform) *Polygon {
+	pts := make([]Point, len(p.points))
+	for i, pt := range p.points {
+		pts[i] = t.Apply(pt)
+	}
+	return build(pts...)
+}
+
+// At returns the polygon, drawn in its own frame, placed at a pose
+func (p *Polygon) At(pose Pose) *Polygon {
+	return p.Transform(pose.Transform())
+}
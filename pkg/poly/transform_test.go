@@ -0,0 +1,89 @@
+package poly
+
+import (
+	"math"
+	"testing"
+)
+
+func pointEq(a, b Point) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+func TestTransform(t *testing.T) {
+	p := Point{1, 2}
+	type test struct {
+		name string
+		t    Transform
+		want Point
+	}
+	tests := []test{
+		{name: "identity", t: Identity(), want: Point{1, 2}},
+		{name: "translate", t: Translate(3, -1), want: Point{4, 1}},
+		{name: "rotate", t: Rotate(math.Pi / 2), want: Point{-2, 1}},
+		{name: "scale", t: Scale(2, -3), want: Point{2, -6}},
+		// rotate first, then translate
+		{name: "compose", t: Translate(3, -1).Compose(Rotate(math.Pi / 2)), want: Point{1, 0}},
+		{name: "then", t: Rotate(math.Pi / 2).Then(Translate(3, -1)), want: Point{1, 0}},
+		// translate first, then rotate
+		{name: "then, the other way", t: Translate(3, -1).Then(Rotate(math.Pi / 2)), want: Point{-1, 4}},
+	}
+	for _, test := range tests {
+		got := test.t.Apply(p)
+		if !pointEq(got, test.want) {
+			t.Fatalf("%s: Apply(%v), expected: %v, got: %v", test.name, p, test.want, got)
+		}
+		inv, ok := test.t.Invert()
+		if !ok || !pointEq(inv.Apply(got), p) {
+			t.Fatalf("%s: Invert(), expected to get %v back, got: %v (%t)", test.name, p, inv.Apply(got), ok)
+		}
+	}
+	if _, ok := Scale(1, 0).Invert(); ok {
+		t.Fatalf("Scale(1, 0).Invert(), expected no inverse")
+	}
+}
+
+func TestPose(t *testing.T) {
+	// facing +Y at 10, 0
+	p := Pose{X: 10, Y: 0, Angle: math.Pi / 2}
+	// one ahead and one to the left, in p's frame
+	q := Pose{X: 1, Y: 1, Angle: math.Pi / 2}
+
+	if got := p.Apply(Point{1, 0}); !pointEq(got, Point{10, 1}) {
+		t.Fatalf("Apply((1, 0)), expected: (10, 1), got: %v", got)
+	}
+	got := p.Compose(q)
+	if !pointEq(Point{got.X, got.Y}, Point{9, 1}) || math.Abs(got.Angle-math.Pi) > 1e-9 {
+		t.Fatalf("Compose(%+v), expected: {9 1 π}, got: %+v", q, got)
+	}
+	id := p.Compose(p.Invert())
+	if !pointEq(Point{id.X, id.Y}, Point{0, 0}) || math.Abs(id.Angle) > 1e-9 {
+		t.Fatalf("Compose(Invert()), expected the identity, got: %+v", id)
+	}
+	pt := Point{3, -4}
+	if got := p.Invert().Apply(p.Apply(pt)); !pointEq(got, pt) {
+		t.Fatalf("Invert().Apply(Apply(%v)), expected: %v, got: %v", pt, pt, got)
+	}
+}
+
+func TestPolygonAt(t *testing.T) {
+	// a 2 by 4 box centered on its own origin, long side along X
+	box := MustNew(Point{-1, -2}, Point{-1, 2}, Point{1, 2}, Point{1, -2})
+	x1, y1, x2, y2 := box.BoundingBox()
+
+	moved := box.At(Pose{X: 10, Y: 5, Angle: math.Pi / 2})
+	mx1, my1, mx2, my2 := moved.BoundingBox()
+	if !pointEq(Point{mx1, my1}, Point{8, 4}) || !pointEq(Point{mx2, my2}, Point{12, 6}) {
+		t.Fatalf("At(), expected a bounding box of (8, 4) to (12, 6), got: (%v, %v) to (%v, %v)", mx1, my1, mx2, my2)
+	}
+	if e := moved.Edges()[0]; !pointEq(e.A, Point{12, 4}) || !pointEq(e.B, Point{8, 4}) {
+		t.Fatalf("At(), expected the first edge from (12, 4) to (8, 4), got: %v", e)
+	}
+	if !moved.Contains(Point{11.9, 5}) || moved.Contains(Point{10, 6.1}) {
+		t.Fatalf("At(), expected the box to lie along Y")
+	}
+	// the original is untouched
+	ox1, oy1, ox2, oy2 := box.BoundingBox()
+	if ox1 != x1 || oy1 != y1 || ox2 != x2 || oy2 != y2 || !pointEq(box.Edges()[0].A, Point{-1, -2}) {
+		t.Fatalf("At(), expected the original polygon unchanged, got: %v", box.Points())
+	}
+}
@@ -0,0 +1,65 @@
+package replay
+
+import (
+	"os"
+	"testing"
+
+	"github.com/team23asu/pican/pkg/session"
+)
+
+// UPDATE_ENV, when set to 1, makes Check write the golden file instead of comparing against it
+const UPDATE_ENV = "REPLAY_UPDATE"
+
+// Check replays the session in dir through the avoider and fails the test if any chair frame differs
+// from the golden run, or from what was recorded if golden is empty. the golden file is only written when
+// REPLAY_UPDATE=1, to be reviewed and committed, a missing one fails the test.
+func Check(t testing.TB, dir string, a Avoider, golden string) {
+	t.Helper()
+	s, err := session.Open(dir)
+	if err != nil {
+		t.Fatalf("failed to open session %s: %v", dir, err)
+	}
+	got, err := Replay(s, a)
+	if err != nil {
+		t.Fatalf("failed to replay %s: %v", dir, err)
+	}
+
+	var want Run
+	if golden == "" {
+		if want, err = Recorded(s); err != nil {
+			t.Fatalf("failed to read the recorded run of %s: %v", dir, err)
+		}
+	} else {
+		if os.Getenv(UPDATE_ENV) == "1" {
+			f, err := os.Create(golden)
+			if err != nil {
+				t.Fatalf("failed to write golden run: %v", err)
+			}
+			defer f.Close()
+			if err := got.Write(f); err != nil {
+				t.Fatalf("failed to write golden run: %v", err)
+			}
+			t.Logf("wrote %d frames to %s", len(got), golden)
+			return
+		}
+		want, err = Load(golden)
+		if os.IsNotExist(err) {
+			t.Fatalf("golden run %s is missing, run with %s=1 to write it", golden, UPDATE_ENV)
+		}
+		if err != nil {
+			t.Fatalf("failed to load golden run %s: %v", golden, err)
+		}
+	}
+
+	diffs, err := Diff(want, got)
+	if err != nil {
+		t.Fatalf("%s: %v", dir, err)
+	}
+	for i, d := range diffs {
+		if i == 10 {
+			t.Errorf("... and %d more", len(diffs)-i)
+			break
+		}
+		t.Errorf("expected != replayed at %s", d)
+	}
+}
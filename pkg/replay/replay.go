@@ -0,0 +1,228 @@
+package replay
+
+// re-runs the joystick input and sensor readings of a recorded session (see pkg/session) through an avoider,
+// offline, so every test drive can become a regression test for changes to the avoidance.
+//
+// a run is the list of movement frames the avoider sent to the chair, each next to the JSM frame it came from.
+// runs are saved as text, one frame per line: the time into the session in seconds, the JSM frame and the chair frame.
+//
+//	1.250000 02000100#0A64 02000100#0064
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+	"github.com/team23asu/pican/pkg/session"
+)
+
+// Avoider is anything that sits between the JSM and the chair and changes movement frames based on what it senses.
+// demo.Avoider is one.
+type Avoider interface {
+	ObserveSensor(session.Sensor)
+	ObserveChair(session.Chair)
+	ModifyFrame(*can.Frame) *can.Frame
+}
+
+// Clock is implemented by avoiders that want to know when things happened, Replay calls ObserveTime with the time
+// of every record before passing it on
+type Clock interface {
+	ObserveTime(time.Time)
+}
+
+// Output is a movement frame sent to the chair and the JSM frame it came from
+type Output struct {
+	Offset time.Duration // since the start of the session
+	Input  string        // in candump format
+	Output string        // in candump format, empty if the frame was dropped
+}
+
+func (o Output) String() string {
+	out := o.Output
+	if out == "" {
+		out = "-"
+	}
+	return fmt.Sprintf("%.6f %s %s", o.Offset.Seconds(), o.Input, out)
+}
+
+type Run []Output
+
+// Replay feeds the session to the avoider in the order it was recorded: sensor readings and chair state
+// as they come, and every movement frame from the JSM side to ModifyFrame
+func Replay(s *session.Session, a Avoider) (Run, error) {
+	var run Run
+	clock, _ := a.(Clock)
+	err := each(s, func(rec session.Record, f *can.Frame) error {
+		if clock != nil {
+			clock.ObserveTime(rec.Time)
+		}
+		switch rec.Kind {
+		case session.KIND_SENSOR:
+			a.ObserveSensor(*rec.Sensor)
+		case session.KIND_CHAIR:
+			a.ObserveChair(*rec.Chair)
+		case session.KIND_FRAME:
+			if rec.FromSide() != rnet.SIDE_JSM {
+				return nil
+			}
+			o := Output{Offset: rec.Time.Sub(s.Index.Start), Input: rec.Frame}
+			if out := a.ModifyFrame(f); out != nil {
+				o.Output = out.String()
+			}
+			run = append(run, o)
+		}
+		return nil
+	})
+	return run, err
+}
+
+// Recorded is the run as it happened: every movement frame from the JSM side paired with the next one seen
+// on the chair side. frames the gateway dropped or sent late shift the pairing, so compare against a replay
+// of the same avoider before trusting it.
+func Recorded(s *session.Session) (Run, error) {
+	var run Run
+	pending := 0 // index of the oldest input not yet paired with an output
+	err := each(s, func(rec session.Record, f *can.Frame) error {
+		if rec.Kind != session.KIND_FRAME {
+			return nil
+		}
+		switch rec.FromSide() {
+		case rnet.SIDE_JSM:
+			run = append(run, Output{Offset: rec.Time.Sub(s.Index.Start), Input: rec.Frame})
+		case rnet.SIDE_CHAIR:
+			if pending < len(run) {
+				run[pending].Output = rec.Frame
+				pending++
+			}
+		}
+		return nil
+	})
+	return run, err
+}
+
+// each calls fn with every sensor, chair and movement frame record in the session
+func each(s *session.Session, fn func(session.Record, *can.Frame) error) error {
+	rd, err := s.Records()
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var f *can.Frame
+		switch rec.Kind {
+		case session.KIND_SENSOR:
+			if rec.Sensor == nil {
+				continue
+			}
+		case session.KIND_CHAIR:
+			if rec.Chair == nil {
+				continue
+			}
+		case session.KIND_FRAME:
+			if f, err = rec.CANFrame(); err != nil {
+				return fmt.Errorf("%s: %w", rec.Time, err)
+			}
+			if !rnet.IsMovementFrame(f.ID) {
+				continue
+			}
+		default:
+			continue
+		}
+		if err := fn(rec, f); err != nil {
+			return err
+		}
+	}
+}
+
+// Write saves a run, see the package comment for the format
+func (r Run) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, o := range r {
+		fmt.Fprintln(bw, o)
+	}
+	return bw.Flush()
+}
+
+// Read loads a run saved by Write
+func Read(r io.Reader) (Run, error) {
+	var run Run
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected <seconds> <input> <output>, got %q", n, sc.Text())
+		}
+		secs, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		o := Output{Offset: time.Duration(secs * float64(time.Second)).Round(time.Microsecond), Input: fields[1], Output: fields[2]}
+		if o.Output == "-" {
+			o.Output = ""
+		}
+		run = append(run, o)
+	}
+	return run, sc.Err()
+}
+
+// Load reads a run from a file
+func Load(path string) (Run, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Difference is a JSM frame that two runs turned into different chair frames
+type Difference struct {
+	A, B Output
+}
+
+func (d Difference) String() string {
+	// show the joystick positions, they are easier to judge than the payloads
+	return fmt.Sprintf("%.6f %s: %s %s != %s %s", d.A.Offset.Seconds(), d.A.Input, d.A.Output, joystick(d.A.Output), d.B.Output, joystick(d.B.Output))
+}
+
+func joystick(line string) string {
+	f, err := can.FromLog(line)
+	if err != nil || !rnet.IsMovementFrame(f.ID) || f.DLC < 2 {
+		return ""
+	}
+	fwd, side := rnet.ConvertDataToJoy(f.Data[0], f.Data[1])
+	return fmt.Sprintf("(side=%+.2f fwd=%+.2f)", side, fwd)
+}
+
+// Diff compares two runs of the same session frame by frame
+func Diff(a, b Run) ([]Difference, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("runs have %d and %d frames, are they of the same session?", len(a), len(b))
+	}
+	var diffs []Difference
+	for i := range a {
+		if a[i].Input != b[i].Input {
+			return nil, fmt.Errorf("frame %d: inputs differ, %s != %s, are the runs of the same session?", i+1, a[i], b[i])
+		}
+		if a[i].Output != b[i].Output {
+			diffs = append(diffs, Difference{A: a[i], B: b[i]})
+		}
+	}
+	return diffs, nil
+}
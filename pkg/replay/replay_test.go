@@ -0,0 +1,160 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+	"github.com/team23asu/pican/pkg/session"
+)
+
+// halver slows the chair down to half speed while the front sensor sees something
+type halver struct {
+	near bool
+}
+
+func (h *halver) ObserveSensor(s session.Sensor) { h.near = s.Meters < s.Threshold }
+func (h *halver) ObserveChair(session.Chair)     {}
+func (h *halver) ModifyFrame(f *can.Frame) *can.Frame {
+	if h.near {
+		out := *f
+		out.Data[1] = uint8(int8(out.Data[1]) / 2)
+		return &out
+	}
+	return f
+}
+
+// drive records a short test drive towards a wall, with the outputs of a halver
+func drive(t *testing.T) string {
+	dir := t.TempDir()
+	r, err := session.NewRecorder(session.Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	a := &halver{}
+	for i := 0; i < 20; i++ {
+		ts := start.Add(time.Duration(i) * 50 * time.Millisecond)
+		sensor := session.Sensor{Name: "front-center", AimDeg: 90, Meters: 3 - 0.1*float64(i), Threshold: 2}
+		in := &can.Frame{ID: 0x02000100 | can.CAN_EFF_FLAG, DLC: 2, Data: [8]uint8{0, uint8(5 * i)}}
+		a.ObserveSensor(sensor)
+		for _, rec := range []session.Record{
+			{Time: ts, Kind: session.KIND_CHAIR, Chair: &session.Chair{Y: 0.1 * float64(i)}},
+			{Time: ts, Kind: session.KIND_SENSOR, Sensor: &sensor},
+			session.FrameRecord(ts, rnet.SIDE_JSM, "", in),
+			session.FrameRecord(ts, rnet.SIDE_CHAIR, "", a.ModifyFrame(in)),
+			// not a movement frame, left out of runs
+			session.FrameRecord(ts, rnet.SIDE_CHAIR, "", &can.Frame{ID: 0x00E, DLC: 1}),
+		} {
+			if err := r.Record(rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReplay(t *testing.T) {
+	dir := drive(t)
+	s, err := session.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := Recorded(s)
+	if err != nil {
+		t.Fatalf("Recorded: %v", err)
+	}
+	replayed, err := Replay(s, &halver{})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(replayed) != 20 || replayed[12].String() != "0.600000 02000100#003C 02000100#001E" {
+		t.Fatalf("unexpected replay: %v", replayed)
+	}
+	diffs, err := Diff(recorded, replayed)
+	if err != nil || len(diffs) != 0 {
+		t.Fatalf("expected replay to match the recording, got: %v (%v)", diffs, err)
+	}
+
+	// a run survives being saved
+	var buf bytes.Buffer
+	if err := replayed.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if diffs, err := Diff(replayed, loaded); err != nil || len(diffs) != 0 || loaded[19].Offset != 950*time.Millisecond {
+		t.Fatalf("expected loaded run to match, got: %v (%v)", diffs, err)
+	}
+
+	// a change to the avoider shows up in the diff
+	diffs, err = Diff(recorded, mustReplay(t, s, passthrough{}))
+	if err != nil || len(diffs) != 9 {
+		t.Fatalf("expected 9 differences once the halver is gone, got: %v (%v)", diffs, err)
+	}
+	if got := diffs[0].String(); got != "0.550000 02000100#0037: 02000100#001B (side=+0.00 fwd=+0.27) != 02000100#0037 (side=+0.00 fwd=+0.55)" {
+		t.Fatalf("unexpected difference: %s", got)
+	}
+}
+
+type passthrough struct{}
+
+func (passthrough) ObserveSensor(session.Sensor)        {}
+func (passthrough) ObserveChair(session.Chair)          {}
+func (passthrough) ModifyFrame(f *can.Frame) *can.Frame { return f }
+
+func mustReplay(t *testing.T, s *session.Session, a Avoider) Run {
+	run, err := Replay(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+// fatal stands in for a test to catch Check failing, Fatalf ends the goroutine the way it ends a test
+type fatal struct {
+	testing.TB
+	msg string
+}
+
+func (f *fatal) Fatalf(format string, args ...interface{}) {
+	f.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestCheck(t *testing.T) {
+	dir := drive(t)
+	Check(t, dir, &halver{}, "")
+	Check(t, dir, &halver{}, filepath.Join("testdata", "halver.golden"))
+
+	// a golden file that was never committed fails rather than being written
+	if os.Getenv(UPDATE_ENV) == "1" {
+		return
+	}
+	missing := filepath.Join(t.TempDir(), "missing.golden")
+	f := &fatal{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Check(f, dir, &halver{}, missing)
+	}()
+	<-done
+	if !strings.Contains(f.msg, "missing") {
+		t.Fatalf("Check(%s), expected: missing golden run, got: %q", missing, f.msg)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("Check(%s), expected: no golden run written, got: %v", missing, err)
+	}
+}
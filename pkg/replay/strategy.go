@@ -0,0 +1,46 @@
+package replay
+
+import (
+	"time"
+
+	"github.com/team23asu/pican/pkg/avoid"
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/session"
+)
+
+// strategyAvoider runs an avoidance strategy on the recorded sensor readings, without the demo
+type strategyAvoider struct {
+	strategy avoid.Strategy
+	readings []avoid.Reading // latest reading of every sensor seen so far, in the order they first showed up
+	chair    avoid.State
+	now      time.Time
+}
+
+// Strategy replays a session through an avoidance strategy, like the demo's Avoider does with its own sensors
+func Strategy(s avoid.Strategy) Avoider {
+	return &strategyAvoider{strategy: s}
+}
+
+func (a *strategyAvoider) ObserveSensor(s session.Sensor) {
+	r := avoid.Reading{Name: s.Name, AimDeg: s.AimDeg, Mount: avoid.Vector2D{X: s.MountX, Y: s.MountY}, Meters: s.Meters, Threshold: s.Threshold}
+	for i := range a.readings {
+		if a.readings[i].Name == r.Name {
+			a.readings[i] = r
+			return
+		}
+	}
+	a.readings = append(a.readings, r)
+}
+
+func (a *strategyAvoider) ObserveChair(c session.Chair) {
+	a.chair = avoid.State{BearingDeg: c.BearingDeg, Speed: c.Speed, Velocity: c.Velocity, TurnRate: c.TurnRate}
+}
+
+func (a *strategyAvoider) ObserveTime(t time.Time) {
+	a.now = t
+}
+
+func (a *strategyAvoider) ModifyFrame(f *can.Frame) *can.Frame {
+	out, _ := avoid.Apply(a.strategy, avoid.Input{Readings: a.readings, Chair: a.chair, Time: a.now}, f)
+	return out
+}
@@ -0,0 +1,143 @@
+package rnet
+
+// control messages sent by the JSM besides joystick movement.
+//
+// we have not captured these from our own chair yet, the ids and payloads below are the ones
+// documented by the can2RNET project (https://github.com/redragonx/can2RNET) for the same R-Net
+// generation. like the movement frames, the JSM id sits in the 0x00000F00 nibble.
+// confirm them with candump before relying on them on the chair.
+//
+// TODO: drive profile changes. they aren't in can2RNET's list, capture the bus (candump -L) while stepping the
+// JSM through every drive profile, with no other input, to find the frame and which byte carries the profile.
+
+import (
+	"fmt"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+const (
+	SPEED_ID     uint32 = 0x0A040000 | can.CAN_EFF_FLAG // 0x0A040X00#<percent>, sets the max speed
+	HORN_ON_ID   uint32 = 0x0C040000 | can.CAN_EFF_FLAG // 0x0C040X00#, start sounding the horn
+	HORN_OFF_ID  uint32 = 0x0C040001 | can.CAN_EFF_FLAG // 0x0C040X01#, stop sounding the horn
+	LIGHTS_ID    uint32 = 0x0C000400 | can.CAN_EFF_FLAG // 0x0C00040L#, toggles light L, no JSM id
+	LIGHTS_MASK  uint32 = 0x0000000F
+	CONTROL_MASK uint32 = ^JSM_ID_MASK // everything except the JSM id nibble
+
+	// the speed setting is sent as a percentage, the chair steps through it in 5 levels
+	SPEED_LEVELS      = 5
+	SPEED_PERCENT_MAX = 100
+)
+
+// Light is one of the chair's lights, as it appears in the lowest nibble of a lights frame
+type Light uint8
+
+const (
+	LIGHT_LEFT_INDICATOR  Light = 0x1
+	LIGHT_RIGHT_INDICATOR Light = 0x2
+	LIGHT_FLOOD           Light = 0x3
+	LIGHT_HAZARD          Light = 0x4
+)
+
+func (l Light) String() string {
+	switch l {
+	case LIGHT_LEFT_INDICATOR:
+		return "left indicator"
+	case LIGHT_RIGHT_INDICATOR:
+		return "right indicator"
+	case LIGHT_FLOOD:
+		return "flood lights"
+	case LIGHT_HAZARD:
+		return "hazard lights"
+	default:
+		return fmt.Sprintf("light 0x%x", uint8(l))
+	}
+}
+
+// ControlKind tells us which field of a Control is meaningful
+type ControlKind int
+
+const (
+	CONTROL_SPEED ControlKind = iota + 1
+	CONTROL_HORN
+	CONTROL_LIGHT
+)
+
+// Control is a decoded non-movement command from the JSM
+type Control struct {
+	Kind  ControlKind
+	JID   uint8 // JSM id, zero for lights
+	Speed uint8 // speed setting in percent, for CONTROL_SPEED
+	Horn  bool  // true when the horn starts, false when it stops, for CONTROL_HORN
+	Light Light // light being toggled, for CONTROL_LIGHT
+}
+
+// DecodeControl recognizes speed, horn and lights frames. It returns false for anything else,
+// including frames with one of our ids but an unexpected payload length or a value out of range.
+func DecodeControl(f *can.Frame) (Control, bool) {
+	switch {
+	case f.ID&CONTROL_MASK == SPEED_ID:
+		if f.DLC != 1 {
+			return Control{}, false
+		}
+		if f.Data[0] > SPEED_PERCENT_MAX {
+			return Control{}, false
+		}
+		return Control{Kind: CONTROL_SPEED, JID: GetJID(f.ID), Speed: f.Data[0]}, true
+	case f.ID&CONTROL_MASK == HORN_ON_ID, f.ID&CONTROL_MASK == HORN_OFF_ID:
+		if f.DLC != 0 {
+			return Control{}, false
+		}
+		return Control{Kind: CONTROL_HORN, JID: GetJID(f.ID), Horn: f.ID&CONTROL_MASK == HORN_ON_ID}, true
+	case f.ID&^LIGHTS_MASK == LIGHTS_ID:
+		l := Light(f.ID & LIGHTS_MASK)
+		if f.DLC != 0 || l < LIGHT_LEFT_INDICATOR || l > LIGHT_HAZARD {
+			return Control{}, false
+		}
+		return Control{Kind: CONTROL_LIGHT, Light: l}, true
+	}
+	return Control{}, false
+}
+
+// Frame encodes the control message as it would be sent by the JSM
+func (c Control) Frame() (*can.Frame, error) {
+	jid := uint32(c.JID&0xF) << 8
+	switch c.Kind {
+	case CONTROL_SPEED:
+		if c.Speed > SPEED_PERCENT_MAX {
+			return nil, fmt.Errorf("speed %d%% out of range", c.Speed)
+		}
+		return &can.Frame{ID: SPEED_ID | jid, DLC: 1, Data: [8]uint8{c.Speed}}, nil
+	case CONTROL_HORN:
+		if c.Horn {
+			return &can.Frame{ID: HORN_ON_ID | jid}, nil
+		}
+		return &can.Frame{ID: HORN_OFF_ID | jid}, nil
+	case CONTROL_LIGHT:
+		if c.Light < LIGHT_LEFT_INDICATOR || c.Light > LIGHT_HAZARD {
+			return nil, fmt.Errorf("unknown %s", c.Light)
+		}
+		return &can.Frame{ID: LIGHTS_ID | uint32(c.Light)}, nil
+	}
+	return nil, fmt.Errorf("unknown control kind %d", c.Kind)
+}
+
+// SpeedLevel converts a speed setting in percent to the chair's speed level (0 through SPEED_LEVELS-1)
+func SpeedLevel(percent uint8) int {
+	if percent > SPEED_PERCENT_MAX {
+		percent = SPEED_PERCENT_MAX
+	}
+	step := SPEED_PERCENT_MAX / (SPEED_LEVELS - 1)
+	return (int(percent) + step/2) / step
+}
+
+// SpeedPercent converts a speed level (0 through SPEED_LEVELS-1) to the percentage sent on the bus
+func SpeedPercent(level int) uint8 {
+	if level < 0 {
+		level = 0
+	}
+	if level >= SPEED_LEVELS {
+		level = SPEED_LEVELS - 1
+	}
+	return uint8(level * SPEED_PERCENT_MAX / (SPEED_LEVELS - 1))
+}
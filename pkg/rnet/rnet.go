@@ -1,5 +1,7 @@
 package rnet
 
+import "math"
+
 const (
 	MAX_XY_DATA int8    = 100
 	MIN_XY_DATA int8    = -100
@@ -14,6 +16,13 @@ const (
 )
 
 func ConvertJoyToData(joyx, joyy float32) (x, y int8) {
+	// a NaN (e.g. from normalizing a zero-length vector) must never move the chair, treat it as centered
+	if joyx != joyx {
+		joyx = 0.0
+	}
+	if joyy != joyy {
+		joyy = 0.0
+	}
 	// dead zone in center position
 	if joyx*joyx <= THRESHOLD_SQ {
 		joyx = 0.0
@@ -38,7 +47,8 @@ func ConvertJoyToData(joyx, joyy float32) (x, y int8) {
 
 	// convert input to value within output range (-100 to 100)
 	factor := (float32(MAX_XY_DATA) - float32(MIN_XY_DATA)) / (MAX_XY_JOY - MIN_XY_JOY)
-	xx, yy := int8(joyx*factor), int8(joyy*factor)
+	// round rather than truncate, otherwise decoding and re-encoding a frame does not give us the same frame
+	xx, yy := int8(math.Round(float64(joyx*factor))), int8(math.Round(float64(joyy*factor)))
 	if xx > LIMIT_X_POS {
 		xx = LIMIT_X_POS
 	}
@@ -78,7 +88,7 @@ const (
 )
 
 func ConvertDataToJoy(xx, yy uint8) (fwd, side float64) {
-	x, y := int8(xx), int8(yy)
+	x, y := clampData(int8(xx)), clampData(int8(yy))
 	if x != 0 {
 		side = INPUT_SCALE_SIDE * float64(x) / 100.0
 	}
@@ -88,6 +98,18 @@ func ConvertDataToJoy(xx, yy uint8) (fwd, side float64) {
 	return fwd, side
 }
 
+// clampData limits a raw payload byte to the valid -100 to 100 range.
+// anything outside of it is corrupt, but it must never make the chair move faster than full deflection.
+func clampData(v int8) int8 {
+	if v > MAX_XY_DATA {
+		return MAX_XY_DATA
+	}
+	if v < MIN_XY_DATA {
+		return MIN_XY_DATA
+	}
+	return v
+}
+
 // // we assume a signed hex input between -100 (0x9C) and 100 (0x64)
 // // note: +100 = 0x64 and -100 = 0x9C (two's complement of 0x64)
 // func GetXY(id uint32) (x, y int8) {
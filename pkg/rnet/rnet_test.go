@@ -1,8 +1,10 @@
 package rnet
 
 import (
+	"math"
 	"testing"
 
+	"github.com/team23asu/pican/pkg/can"
 	"golang.org/x/sys/unix"
 )
 
@@ -56,3 +58,156 @@ func TestIsMovementFrame(t *testing.T) {
 		}
 	}
 }
+
+// joyLimits returns the largest magnitude accepted for each axis after ConvertJoyToData clamps its output
+func joyLimits() (maxX, maxY float64) {
+	return float64(LIMIT_X_POS) / float64(MAX_XY_DATA), float64(LIMIT_Y_POS) / float64(MAX_XY_DATA)
+}
+
+func FuzzConvertJoyToData(f *testing.F) {
+	for _, seed := range [][2]float32{
+		{0, 0}, {0.05, -0.05}, {0.1, 0.1}, {0.3, -0.3}, {1.0, 1.0}, {-1.0, -1.0}, {1.2, -1.2},
+	} {
+		f.Add(seed[0], seed[1], seed[0]+0.01, seed[1]+0.01)
+	}
+	nan, inf := float32(math.NaN()), float32(math.Inf(1))
+	f.Add(nan, -nan, inf, -inf)
+	f.Add(inf, -inf, nan, float32(0.5))
+
+	f.Fuzz(func(t *testing.T, joyx, joyy, joyx2, joyy2 float32) {
+		x, y := ConvertJoyToData(joyx, joyy)
+
+		// limits are never exceeded, even for NaN and Inf
+		if x > LIMIT_X_POS || x < LIMIT_X_NEG || y > LIMIT_Y_POS || y < LIMIT_Y_NEG {
+			t.Fatalf("ConvertJoyToData(%v, %v) = (%d, %d), out of limits", joyx, joyy, x, y)
+		}
+		if joyx != joyx || joyy != joyy {
+			// NaN must never move the chair
+			if (joyx != joyx && x != 0) || (joyy != joyy && y != 0) {
+				t.Fatalf("ConvertJoyToData(%v, %v) = (%d, %d), expected NaN to be neutral", joyx, joyy, x, y)
+			}
+			return
+		}
+
+		// a larger input never produces a smaller output
+		if joyx2 == joyx2 && joyy2 == joyy2 {
+			x2, y2 := ConvertJoyToData(joyx2, joyy2)
+			if (joyx < joyx2 && x > x2) || (joyx > joyx2 && x < x2) {
+				t.Fatalf("ConvertJoyToData not monotonic in x: %v -> %d, %v -> %d", joyx, x, joyx2, x2)
+			}
+			if (joyy < joyy2 && y > y2) || (joyy > joyy2 && y < y2) {
+				t.Fatalf("ConvertJoyToData not monotonic in y: %v -> %d, %v -> %d", joyy, y, joyy2, y2)
+			}
+		}
+
+		// decoding gets us back to the (clamped) input, to within one data step
+		fwd, side := ConvertDataToJoy(uint8(x), uint8(y))
+		maxX, maxY := joyLimits()
+		wantX := math.Max(-maxX, math.Min(maxX, float64(joyx)))
+		wantY := math.Max(-maxY, math.Min(maxY, float64(joyy)))
+		step := 1.0 / float64(MAX_XY_DATA)
+		if x != 0 && math.Abs(side*INPUT_SCALE_SIDE-wantX) > step {
+			t.Fatalf("ConvertJoyToData(%v, _) = %d, decodes to side %v", joyx, x, side)
+		}
+		if y != 0 && math.Abs(fwd*INPUT_SCALE_FWD-wantY) > step {
+			t.Fatalf("ConvertJoyToData(_, %v) = %d, decodes to fwd %v", joyy, y, fwd)
+		}
+	})
+}
+
+func FuzzConvertDataToJoy(f *testing.F) {
+	for _, seed := range [][2]uint8{
+		{0x00, 0x00}, {0x03, 0x28}, {0x9C, 0x00}, {0x60, 0xEE}, {0x64, 0x9C},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, xx, yy uint8) {
+		fwd, side := ConvertDataToJoy(xx, yy)
+
+		// limits are never exceeded, even for bytes outside of the -100 to 100 range
+		if math.Abs(fwd) > float64(MAX_XY_JOY) || math.Abs(side) > float64(MAX_XY_JOY) {
+			t.Fatalf("ConvertDataToJoy(0x%.2x, 0x%.2x) = (%v, %v), out of limits", xx, yy, fwd, side)
+		}
+
+		// re-encoding a valid payload gives us the same payload back,
+		// as long as it is outside the dead zone
+		x, y := int8(xx), int8(yy)
+		inRange := x <= LIMIT_X_POS && x >= LIMIT_X_NEG && y <= LIMIT_Y_POS && y >= LIMIT_Y_NEG
+		if !inRange || float32(fwd*fwd) <= THRESHOLD_SQ || float32(side*side) <= THRESHOLD_SQ {
+			return
+		}
+		gotX, gotY := ConvertJoyToData(float32(side*INPUT_SCALE_SIDE), float32(fwd*INPUT_SCALE_FWD))
+		if gotX != x || gotY != y {
+			t.Fatalf("round trip 0x%.2x%.2x -> (%v, %v) -> 0x%.2x%.2x", xx, yy, fwd, side, uint8(gotX), uint8(gotY))
+		}
+	})
+}
+
+func TestControlRoundTrip(t *testing.T) {
+	tests := []Control{
+		{Kind: CONTROL_SPEED, JID: 1, Speed: 0},
+		{Kind: CONTROL_SPEED, JID: 1, Speed: 75},
+		{Kind: CONTROL_SPEED, JID: 0xE, Speed: 100},
+		{Kind: CONTROL_HORN, JID: 1, Horn: true},
+		{Kind: CONTROL_HORN, JID: 2, Horn: false},
+		{Kind: CONTROL_LIGHT, Light: LIGHT_LEFT_INDICATOR},
+		{Kind: CONTROL_LIGHT, Light: LIGHT_HAZARD},
+	}
+
+	for _, test := range tests {
+		f, err := test.Frame()
+		if err != nil {
+			t.Fatalf("%+v.Frame(), unexpected error: %v", test, err)
+		}
+		got, ok := DecodeControl(f)
+		if !ok || got != test {
+			t.Fatalf("DecodeControl(%s), expected: %+v, got: %+v (%t)", f, test, got, ok)
+		}
+	}
+}
+
+func TestDecodeControl(t *testing.T) {
+	type test struct {
+		line string
+		want Control
+		ok   bool
+	}
+
+	tests := []test{
+		{line: "0A040100#32", want: Control{Kind: CONTROL_SPEED, JID: 1, Speed: 50}, ok: true},
+		{line: "0A040100#64", want: Control{Kind: CONTROL_SPEED, JID: 1, Speed: 100}, ok: true},
+		{line: "0A040100#65", ok: false},
+		{line: "0A040100#FF", ok: false},
+		{line: "0A040100#3200", ok: false},
+		{line: "0C040100#00", ok: false}, // horn frames carry no data
+		{line: "02000100#0064", ok: false},
+		{line: "00E#048C1CBC00000000", ok: false},
+	}
+
+	for _, test := range tests {
+		f, err := can.FromLog(test.line)
+		if err != nil {
+			t.Fatalf("FromLog(%q): %v", test.line, err)
+		}
+		got, ok := DecodeControl(f)
+		if ok != test.ok || got != test.want {
+			t.Fatalf("DecodeControl(%q), expected: %+v (%t), got: %+v (%t)", test.line, test.want, test.ok, got, ok)
+		}
+	}
+
+	if _, ok := DecodeControl(&can.Frame{ID: HORN_ON_ID | 0x100}); !ok {
+		t.Fatalf("expected horn frame to decode")
+	}
+}
+
+func TestSpeedLevel(t *testing.T) {
+	for level := 0; level < SPEED_LEVELS; level++ {
+		if got := SpeedLevel(SpeedPercent(level)); got != level {
+			t.Fatalf("SpeedLevel(SpeedPercent(%d)) = %d", level, got)
+		}
+	}
+	if got := SpeedLevel(255); got != SPEED_LEVELS-1 {
+		t.Fatalf("SpeedLevel(255), expected: %d, got: %d", SPEED_LEVELS-1, got)
+	}
+}
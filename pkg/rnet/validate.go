@@ -0,0 +1,330 @@
+package rnet
+
+// checks a stream of frames against what we know about R-Net, so we can tell whether
+// frames leaving the gateway still look like they came from a legitimate JSM.
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+// Side identifies a segment of the bus on either side of the gateway
+type Side int
+
+const (
+	SIDE_ANY   Side = iota // unknown, or a capture of the bus without a gateway
+	SIDE_JSM               // the segment with the joystick module
+	SIDE_CHAIR             // the segment with the power module and everything else
+)
+
+func (s Side) String() string {
+	switch s {
+	case SIDE_JSM:
+		return "jsm"
+	case SIDE_CHAIR:
+		return "chair"
+	default:
+		return "any"
+	}
+}
+
+// Observation is a frame seen on one side of the gateway at a given time. frames the gateway forwards show
+// up on both sides, a movement frame seen on the chair side is fine as long as it was just sent from the jsm side.
+type Observation struct {
+	Time  time.Time
+	Side  Side
+	Frame can.Frame
+}
+
+// Spec describes what a known message should look like
+type Spec struct {
+	Name      string
+	ID, Mask  uint32        // a frame matches when frame.ID&Mask == ID
+	DLC       int           // expected data length, -1 if it varies
+	Period    time.Duration // expected time between frames, 0 if not periodic
+	Tolerance time.Duration // allowed deviation from Period
+	Origin    Side          // side the message is sent from, SIDE_ANY if either
+}
+
+// MAX_FORWARD_DELAY is how long after a frame is sent from one side of the gateway its forwarded copy can show up
+// on the other side. well over the gateway's own DEFAULT_MAX_LATENCY, captures aren't timestamped that precisely.
+const MAX_FORWARD_DELAY = 20 * time.Millisecond
+
+func (s Spec) Matches(f can.Frame) bool {
+	return f.ID&^can.CAN_RTR_FLAG&s.Mask == s.ID
+}
+
+// DefaultSpecs returns the messages we have identified, see docs/JSM_*.csv and control.go
+func DefaultSpecs() []Spec {
+	return []Spec{
+		{Name: "movement", ID: ARB_ID_MASK &^ JSM_ID_MASK, Mask: ^JSM_ID_MASK, DLC: 2, Period: 10 * time.Millisecond, Tolerance: 5 * time.Millisecond, Origin: SIDE_JSM},
+		{Name: "speed", ID: SPEED_ID, Mask: CONTROL_MASK, DLC: 1, Origin: SIDE_JSM},
+		{Name: "horn on", ID: HORN_ON_ID, Mask: CONTROL_MASK, DLC: 0, Origin: SIDE_JSM},
+		{Name: "horn off", ID: HORN_OFF_ID, Mask: CONTROL_MASK, DLC: 0, Origin: SIDE_JSM},
+		{Name: "lights", ID: LIGHTS_ID, Mask: ^LIGHTS_MASK, DLC: 0, Origin: SIDE_JSM},
+		{Name: "status", ID: 0x00E, Mask: 0xFFFFFFFF, DLC: 8, Period: 50 * time.Millisecond, Tolerance: 10 * time.Millisecond},
+		{Name: "3C30F0F", ID: 0x03C30F0F | can.CAN_EFF_FLAG, Mask: 0xFFFFFFFF, DLC: 7},
+		{Name: "140C0001", ID: 0x140C0001 | can.CAN_EFF_FLAG, Mask: 0xFFFFFFFF, DLC: 2},
+	}
+}
+
+type ViolationKind int
+
+const (
+	VIOLATION_DLC ViolationKind = iota
+	VIOLATION_AXIS_RANGE
+	VIOLATION_PERIOD
+	VIOLATION_SEQUENCE
+	VIOLATION_SIDE
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case VIOLATION_DLC:
+		return "wrong DLC"
+	case VIOLATION_AXIS_RANGE:
+		return "axis out of range"
+	case VIOLATION_PERIOD:
+		return "period out of tolerance"
+	case VIOLATION_SEQUENCE:
+		return "sequence anomaly"
+	case VIOLATION_SIDE:
+		return "wrong side"
+	default:
+		return fmt.Sprintf("violation %d", int(k))
+	}
+}
+
+type Violation struct {
+	Observation
+	Kind   ViolationKind
+	Detail string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s [%s] %s: %s: %s", v.Time.Format("15:04:05.000000"), v.Side, v.Frame, v.Kind, v.Detail)
+}
+
+type streamKey struct {
+	side Side
+	id   uint32
+}
+
+// Validator checks observations one at a time, remembering what it needs to check periods and sequences
+type Validator struct {
+	specs   []Spec
+	last    map[streamKey]time.Time
+	sent    map[streamKey]time.Time // last time each known id was seen, by side
+	lastJID map[Side]uint8
+	horn    map[Side]bool
+	prev    time.Time
+}
+
+func NewValidator(specs []Spec) *Validator {
+	return &Validator{
+		specs:   specs,
+		last:    make(map[streamKey]time.Time),
+		sent:    make(map[streamKey]time.Time),
+		lastJID: make(map[Side]uint8),
+		horn:    make(map[Side]bool),
+	}
+}
+
+// Check returns the violations found in o, given everything observed before it
+func (v *Validator) Check(o Observation) []Violation {
+	var found []Violation
+	flag := func(kind ViolationKind, format string, args ...interface{}) {
+		found = append(found, Violation{Observation: o, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if !v.prev.IsZero() && o.Time.Before(v.prev) {
+		flag(VIOLATION_SEQUENCE, "timestamp %s before previous frame", v.prev.Sub(o.Time))
+	}
+	v.prev = o.Time
+
+	f := o.Frame
+	spec, ok := v.lookup(f)
+	if !ok {
+		// not a message we know, nothing to check
+		return found
+	}
+
+	if spec.Origin != SIDE_ANY && o.Side != SIDE_ANY && o.Side != spec.Origin {
+		// on the wrong side it has to be the gateway forwarding what was just sent from the right one
+		sent, ok := v.sent[streamKey{side: spec.Origin, id: f.ID}]
+		if !ok || o.Time.Sub(sent) > MAX_FORWARD_DELAY {
+			flag(VIOLATION_SIDE, "%s is sent from the %s side, and wasn't forwarded from it", spec.Name, spec.Origin)
+		}
+	}
+	v.sent[streamKey{side: o.Side, id: f.ID}] = o.Time
+	if spec.DLC >= 0 && !f.IsRemote() && int(f.DLC) != spec.DLC {
+		flag(VIOLATION_DLC, "%s expects %d bytes, got %d", spec.Name, spec.DLC, f.DLC)
+	}
+
+	if spec.Period > 0 && !f.IsRemote() {
+		key := streamKey{side: o.Side, id: f.ID}
+		if last, ok := v.last[key]; ok {
+			gap := o.Time.Sub(last)
+			if gap < spec.Period-spec.Tolerance || gap > spec.Period+spec.Tolerance {
+				flag(VIOLATION_PERIOD, "%s expected every %s ±%s, got %s", spec.Name, spec.Period, spec.Tolerance, gap)
+			}
+		}
+		v.last[key] = o.Time
+	}
+
+	switch {
+	case IsMovementFrame(f.ID) && f.DLC == 2:
+		x, y := int8(f.Data[0]), int8(f.Data[1])
+		if x > MAX_XY_DATA || x < MIN_XY_DATA || y > MAX_XY_DATA || y < MIN_XY_DATA {
+			flag(VIOLATION_AXIS_RANGE, "axis (%d, %d) outside %d to %d", x, y, MIN_XY_DATA, MAX_XY_DATA)
+		}
+		jid := GetJID(f.ID)
+		if last, ok := v.lastJID[o.Side]; ok && last != jid {
+			flag(VIOLATION_SEQUENCE, "JSM id changed from %x to %x", last, jid)
+		}
+		v.lastJID[o.Side] = jid
+	case f.ID&CONTROL_MASK == HORN_ON_ID:
+		v.horn[o.Side] = true
+	case f.ID&CONTROL_MASK == HORN_OFF_ID:
+		if !v.horn[o.Side] {
+			flag(VIOLATION_SEQUENCE, "horn stopped without being started")
+		}
+		v.horn[o.Side] = false
+	}
+	return found
+}
+
+func (v *Validator) lookup(f can.Frame) (Spec, bool) {
+	for _, s := range v.specs {
+		if s.Matches(f) {
+			return s, true
+		}
+	}
+	return Spec{}, false
+}
+
+// IDStats summarizes the frames seen with a single id
+type IDStats struct {
+	ID     uint32
+	Count  int
+	MaxGap time.Duration
+	first  time.Time
+	last   time.Time
+}
+
+// MeanPeriod returns the average time between frames, or 0 with fewer than two frames
+func (s *IDStats) MeanPeriod() time.Duration {
+	if s.Count < 2 {
+		return 0
+	}
+	return s.last.Sub(s.first) / time.Duration(s.Count-1)
+}
+
+// Summary is the result of validating a whole capture
+type Summary struct {
+	Frames     int
+	Unknown    int // frames that match none of the specs
+	Violations []Violation
+	ByKind     map[ViolationKind]int
+	ByID       map[uint32]*IDStats
+}
+
+// Validate runs every observation through a fresh Validator
+func Validate(specs []Spec, obs []Observation) *Summary {
+	v := NewValidator(specs)
+	s := &Summary{
+		ByKind: make(map[ViolationKind]int),
+		ByID:   make(map[uint32]*IDStats),
+	}
+	for _, o := range obs {
+		s.Frames++
+		if _, ok := v.lookup(o.Frame); !ok {
+			s.Unknown++
+		}
+		for _, found := range v.Check(o) {
+			s.Violations = append(s.Violations, found)
+			s.ByKind[found.Kind]++
+		}
+		st, ok := s.ByID[o.Frame.ID]
+		if !ok {
+			st = &IDStats{ID: o.Frame.ID, first: o.Time}
+			s.ByID[o.Frame.ID] = st
+		} else if gap := o.Time.Sub(st.last); gap > st.MaxGap {
+			st.MaxGap = gap
+		}
+		st.Count++
+		st.last = o.Time
+	}
+	return s
+}
+
+// Report compares a capture taken before the gateway (or without it) with one taken after it
+type Report struct {
+	Before, After *Summary
+}
+
+func Compare(specs []Spec, before, after []Observation) *Report {
+	return &Report{
+		Before: Validate(specs, before),
+		After:  Validate(specs, after),
+	}
+}
+
+// Write prints the report as a pair of tables: violations by kind and traffic by id
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "\tbefore\tafter\t\n")
+	fmt.Fprintf(tw, "frames\t%d\t%d\t\n", r.Before.Frames, r.After.Frames)
+	fmt.Fprintf(tw, "unknown ids\t%d\t%d\t\n", r.Before.Unknown, r.After.Unknown)
+	for k := VIOLATION_DLC; k <= VIOLATION_SIDE; k++ {
+		note := ""
+		if r.After.ByKind[k] > r.Before.ByKind[k] {
+			note = "<- introduced by gateway"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", k, r.Before.ByKind[k], r.After.ByKind[k], note)
+	}
+	fmt.Fprintf(tw, "\t\t\t\n")
+
+	ids := make(map[uint32]bool)
+	for id := range r.Before.ByID {
+		ids[id] = true
+	}
+	for id := range r.After.ByID {
+		ids[id] = true
+	}
+	sorted := make([]uint32, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	fmt.Fprintf(tw, "id\tbefore count/period\tafter count/period\t\n")
+	for _, id := range sorted {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", idString(id), statsString(r.Before.ByID[id]), statsString(r.After.ByID[id]))
+	}
+	return tw.Flush()
+}
+
+// idString formats an id the way candump does, marking remote frames with an R
+func idString(id uint32) string {
+	s := fmt.Sprintf("%03X", id&can.CAN_SFF_MASK)
+	if id&can.CAN_EFF_FLAG != 0 {
+		s = fmt.Sprintf("%08X", id&can.CAN_EFF_MASK)
+	}
+	if id&can.CAN_RTR_FLAG != 0 {
+		s += " R"
+	}
+	return s
+}
+
+func statsString(s *IDStats) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d / %s", s.Count, s.MeanPeriod())
+}
@@ -0,0 +1,150 @@
+package rnet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+)
+
+func observe(t *testing.T, start time.Time, side Side, ms int, line string) Observation {
+	f, err := can.FromLog(line)
+	if err != nil {
+		t.Fatalf("FromLog(%q): %v", line, err)
+	}
+	return Observation{Time: start.Add(time.Duration(ms) * time.Millisecond), Side: side, Frame: *f}
+}
+
+func TestValidator(t *testing.T) {
+	type test struct {
+		name string
+		obs  []Observation
+		want []ViolationKind
+	}
+
+	start := time.Unix(0, 0)
+	tests := []test{
+		{
+			name: "clean",
+			obs: []Observation{
+				observe(t, start, SIDE_JSM, 0, "02000100#0000"),
+				observe(t, start, SIDE_JSM, 10, "02000100#0328"),
+				observe(t, start, SIDE_CHAIR, 12, "00E#048C1CBC00000000"),
+				observe(t, start, SIDE_JSM, 20, "02000100#9C00"),
+				observe(t, start, SIDE_JSM, 25, "0C040100#"),
+			},
+		},
+		{
+			name: "dlc",
+			obs:  []Observation{observe(t, start, SIDE_JSM, 0, "02000100#000000")},
+			want: []ViolationKind{VIOLATION_DLC},
+		},
+		{
+			name: "axis",
+			obs:  []Observation{observe(t, start, SIDE_JSM, 0, "02000100#8000")},
+			want: []ViolationKind{VIOLATION_AXIS_RANGE},
+		},
+		{
+			name: "period",
+			obs: []Observation{
+				observe(t, start, SIDE_JSM, 0, "02000100#0000"),
+				observe(t, start, SIDE_JSM, 50, "02000100#0000"),
+			},
+			want: []ViolationKind{VIOLATION_PERIOD},
+		},
+		{
+			name: "sequence",
+			obs: []Observation{
+				observe(t, start, SIDE_JSM, 10, "02000100#0000"),
+				observe(t, start, SIDE_JSM, 0, "02000200#0000"),
+				observe(t, start, SIDE_JSM, 20, "0C040101#"),
+			},
+			want: []ViolationKind{VIOLATION_SEQUENCE, VIOLATION_SEQUENCE, VIOLATION_SEQUENCE},
+		},
+		{
+			name: "side",
+			obs:  []Observation{observe(t, start, SIDE_CHAIR, 0, "02000100#0000")},
+			want: []ViolationKind{VIOLATION_SIDE},
+		},
+	}
+
+	for _, test := range tests {
+		s := Validate(DefaultSpecs(), test.obs)
+		if len(s.Violations) != len(test.want) {
+			t.Fatalf("%s: expected: %v, got: %v", test.name, test.want, s.Violations)
+		}
+		for i, v := range s.Violations {
+			if v.Kind != test.want[i] {
+				t.Fatalf("%s: expected: %v, got: %v", test.name, test.want, s.Violations)
+			}
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	start := time.Unix(0, 0)
+	before := []Observation{
+		observe(t, start, SIDE_JSM, 0, "02000100#0328"),
+		observe(t, start, SIDE_JSM, 10, "02000100#0328"),
+	}
+	after := []Observation{
+		observe(t, start, SIDE_JSM, 0, "02000100#0328"),
+		observe(t, start, SIDE_JSM, 40, "02000100#0328"),
+	}
+	buf := &bytes.Buffer{}
+	err := Compare(DefaultSpecs(), before, after).Write(buf)
+	if err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	if !strings.Contains(buf.String(), "introduced by gateway") || !strings.Contains(buf.String(), "02000100") {
+		t.Fatalf("expected report to flag the late frame, got:\n%s", buf)
+	}
+}
+
+// a capture of both sides of the gateway, which forwards the JSM's frames to the chair and the chair's back,
+// scaling the joystick down on the way
+const forwarded = `
+(0.000000) vcan0 02000100#0064
+(0.000400) vcan1 02000100#0032
+(0.005000) vcan1 00E#048C1CBC00000000
+(0.005300) vcan0 00E#048C1CBC00000000
+(0.010000) vcan0 02000100#0064
+(0.010600) vcan1 02000100#0032
+(0.012000) vcan0 0A040100#32
+(0.012300) vcan1 0A040100#32
+(0.015000) vcan0 0C040100#
+(0.015200) vcan1 0C040100#
+(0.020000) vcan0 02000100#0A64
+(0.020500) vcan1 02000100#0532
+(0.025000) vcan0 0C040101#
+(0.025300) vcan1 0C040101#
+(0.030000) vcan0 02000100#0000
+(0.030400) vcan1 02000100#0000
+`
+
+func TestValidateForwarded(t *testing.T) {
+	entries, err := can.ReadLog(strings.NewReader(forwarded))
+	if err != nil {
+		t.Fatalf("failed to read capture: %v", err)
+	}
+	var obs []Observation
+	for _, e := range entries {
+		side := SIDE_JSM
+		if e.Iface == "vcan1" {
+			side = SIDE_CHAIR
+		}
+		obs = append(obs, Observation{Time: e.Time, Side: side, Frame: e.Frame})
+	}
+	if s := Validate(DefaultSpecs(), obs); len(s.Violations) != 0 {
+		t.Fatalf("Validate(forwarded), expected: no violations, got: %v", s.Violations)
+	}
+
+	// a movement frame on the chair side the JSM never sent is still flagged
+	spoofed := append(obs, Observation{Time: obs[len(obs)-1].Time.Add(MAX_FORWARD_DELAY + time.Millisecond), Side: SIDE_CHAIR, Frame: obs[0].Frame})
+	s := Validate(DefaultSpecs(), spoofed)
+	if s.ByKind[VIOLATION_SIDE] != 1 {
+		t.Fatalf("Validate(spoofed), expected: 1 side violation, got: %v", s.Violations)
+	}
+}
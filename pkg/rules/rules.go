@@ -0,0 +1,352 @@
+package rules
+
+// declarative frame rules for the gateway, so experiments on the chair don't need a rebuild.
+//
+// a rules file is JSON, with rules applied in order to every frame crossing the gateway:
+//
+//	{
+//	  "rules": [
+//	    {"name": "no horn", "direction": "jsm->chair", "match": {"id": "0C040100", "mask": "FFFFF0FE"}, "action": "drop"},
+//	    {"name": "half speed", "match": {"id": "02000000", "mask": "FFFFF0FF"}, "action": "scale_axis", "axis": "fwd", "factor": 0.5},
+//	    {"name": "beef", "match": {"id": "02000100", "data": "????"}, "action": "rewrite", "data": "BEEF"},
+//	    {"name": "late status", "direction": "chair->jsm", "match": {"id": "00E"}, "action": "delay", "delay": "20ms"},
+//	    {"name": "echo", "match": {"id": "140C0001"}, "action": "duplicate", "count": 2},
+//	    {"name": "ping", "direction": "jsm->chair", "action": "inject", "frame": "0C000404#", "period": "1s"}
+//	  ]
+//	}
+//
+// match.id is written like a candump id, 3 hex chars for a standard id and 8 for an extended one.
+// match.mask defaults to every bit of the id, leave out the id to match every frame.
+// match.data matches payload bytes, with ?? for any byte.
+// a rule without a direction applies both ways, except inject which needs one.
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/gateway"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+const (
+	ACTION_DROP       = "drop"
+	ACTION_REWRITE    = "rewrite"
+	ACTION_SCALE_AXIS = "scale_axis"
+	ACTION_DELAY      = "delay"
+	ACTION_DUPLICATE  = "duplicate"
+	ACTION_INJECT     = "inject"
+)
+
+// Match selects the frames a rule applies to
+type Match struct {
+	ID   string `json:"id"`
+	Mask string `json:"mask"`
+	Data string `json:"data"`
+}
+
+// Rule is a single entry of a rules file, see the package comment for the format
+type Rule struct {
+	Name      string  `json:"name"`
+	Direction string  `json:"direction"`
+	Match     Match   `json:"match"`
+	Action    string  `json:"action"`
+	Data      string  `json:"data"`   // rewrite: new payload bytes, ?? leaves a byte alone
+	Axis      string  `json:"axis"`   // scale_axis: "side" (first byte) or "fwd" (second byte) of a movement frame
+	Factor    float64 `json:"factor"` // scale_axis
+	Delay     string  `json:"delay"`  // delay: how long to hold the frame
+	Count     int     `json:"count"`  // duplicate: number of extra copies
+	Frame     string  `json:"frame"`  // inject: frame to send, in candump format
+	Period    string  `json:"period"` // inject: time between frames
+}
+
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Injector is the part of the gateway that rules use to send frames of their own
+type Injector interface {
+	Inject(dir gateway.Direction, f *can.Frame) error
+}
+
+// compiled is a rule, validated and ready to use
+type compiled struct {
+	Rule
+	dirs     []gateway.Direction
+	id, mask uint32
+	data     pattern
+	rewrite  pattern
+	delay    time.Duration
+	period   time.Duration
+	frame    *can.Frame
+}
+
+// Set is a parsed rules file
+type Set struct {
+	rules []*compiled
+}
+
+// Load reads and parses a rules file
+func Load(path string) (*Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a rules file, checking every rule so that mistakes show up when it is loaded
+func Parse(r io.Reader) (*Set, error) {
+	var file File
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	s := &Set{}
+	for i, rule := range file.Rules {
+		c, err := compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%q): %w", i+1, rule.Name, err)
+		}
+		s.rules = append(s.rules, c)
+	}
+	return s, nil
+}
+
+func compile(r Rule) (*compiled, error) {
+	c := &compiled{Rule: r, mask: 0xFFFFFFFF}
+	switch r.Direction {
+	case "":
+		c.dirs = []gateway.Direction{gateway.JSM_TO_CHAIR, gateway.CHAIR_TO_JSM}
+	case gateway.JSM_TO_CHAIR.String():
+		c.dirs = []gateway.Direction{gateway.JSM_TO_CHAIR}
+	case gateway.CHAIR_TO_JSM.String():
+		c.dirs = []gateway.Direction{gateway.CHAIR_TO_JSM}
+	default:
+		return nil, fmt.Errorf("direction must be %q or %q", gateway.JSM_TO_CHAIR, gateway.CHAIR_TO_JSM)
+	}
+
+	var err error
+	if r.Action != ACTION_INJECT {
+		if c.id, c.mask, err = parseID(r.Match.ID, r.Match.Mask); err != nil {
+			return nil, err
+		}
+		if c.data, err = parsePattern(r.Match.Data); err != nil {
+			return nil, fmt.Errorf("match data: %w", err)
+		}
+	}
+
+	switch r.Action {
+	case ACTION_DROP:
+	case ACTION_REWRITE:
+		if c.rewrite, err = parsePattern(r.Data); err != nil || len(c.rewrite) == 0 {
+			return nil, fmt.Errorf("rewrite needs data, e.g. \"BE??\": %v", err)
+		}
+	case ACTION_SCALE_AXIS:
+		if r.Axis != "side" && r.Axis != "fwd" {
+			return nil, fmt.Errorf("scale_axis needs axis \"side\" or \"fwd\"")
+		}
+		if math.IsNaN(r.Factor) || math.IsInf(r.Factor, 0) {
+			return nil, fmt.Errorf("scale_axis needs a finite factor")
+		}
+	case ACTION_DELAY:
+		if c.delay, err = time.ParseDuration(r.Delay); err != nil || c.delay <= 0 {
+			return nil, fmt.Errorf("delay needs a positive duration: %v", err)
+		}
+	case ACTION_DUPLICATE:
+		if r.Count < 1 {
+			return nil, fmt.Errorf("duplicate needs a count of at least 1")
+		}
+	case ACTION_INJECT:
+		if len(c.dirs) != 1 {
+			return nil, fmt.Errorf("inject needs a direction")
+		}
+		if c.frame, err = can.FromLog(r.Frame); err != nil {
+			return nil, fmt.Errorf("inject frame: %w", err)
+		}
+		if c.period, err = time.ParseDuration(r.Period); err != nil || c.period <= 0 {
+			return nil, fmt.Errorf("inject needs a positive period: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+	return c, nil
+}
+
+func parseID(id, mask string) (uint32, uint32, error) {
+	if id == "" {
+		if mask != "" {
+			return 0, 0, fmt.Errorf("match mask needs an id")
+		}
+		// match everything
+		return 0, 0, nil
+	}
+	// let FromLog deal with checking and flagging the id
+	f, err := can.FromLog(id + "#")
+	if err != nil {
+		return 0, 0, fmt.Errorf("match id: %w", err)
+	}
+	m := uint32(0xFFFFFFFF)
+	if mask != "" {
+		v, err := strconv.ParseUint(mask, 16, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("match mask: %w", err)
+		}
+		// the flags are never masked off, a standard id must not match an extended one
+		m = uint32(v) | can.CAN_EFF_FLAG | can.CAN_RTR_FLAG
+	}
+	return f.ID & m, m, nil
+}
+
+// pattern is a list of payload bytes, with -1 for ?? (any byte)
+type pattern []int
+
+func parsePattern(s string) (pattern, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s)%2 != 0 || len(s) > 16 {
+		return nil, fmt.Errorf("expected up to 8 bytes as hex pairs, got %q", s)
+	}
+	p := make(pattern, 0, len(s)/2)
+	for i := 0; i < len(s); i += 2 {
+		if s[i:i+2] == "??" {
+			p = append(p, -1)
+			continue
+		}
+		b, err := hex.DecodeString(s[i : i+2])
+		if err != nil {
+			return nil, fmt.Errorf("bad byte %q", s[i:i+2])
+		}
+		p = append(p, int(b[0]))
+	}
+	return p, nil
+}
+
+func (c *compiled) matches(f *can.Frame) bool {
+	if f.ID&c.mask != c.id {
+		return false
+	}
+	if len(c.data) > int(f.DLC) {
+		return false
+	}
+	for i, b := range c.data {
+		if b >= 0 && f.Data[i] != uint8(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// processor turns the rule into a gateway processor for one direction
+func (c *compiled) processor(dir gateway.Direction, inj Injector) gateway.Processor {
+	return gateway.ProcessorFunc(func(f *can.Frame, emit gateway.Emit) gateway.Verdict {
+		if !c.matches(f) {
+			return gateway.PASS
+		}
+		switch c.Action {
+		case ACTION_DROP:
+			return gateway.DROP
+		case ACTION_REWRITE:
+			for i, b := range c.rewrite {
+				if b >= 0 {
+					f.Data[i] = uint8(b)
+				}
+			}
+			if int(f.DLC) < len(c.rewrite) {
+				f.DLC = uint8(len(c.rewrite))
+			}
+		case ACTION_SCALE_AXIS:
+			if rnet.IsMovementFrame(f.ID) && f.DLC >= 2 {
+				i := 0
+				if c.Axis == "fwd" {
+					i = 1
+				}
+				f.Data[i] = uint8(scale(int8(f.Data[i]), c.Factor))
+			}
+		case ACTION_DELAY:
+			late := *f
+			time.AfterFunc(c.delay, func() {
+				if err := inj.Inject(dir, &late); err != nil {
+					log.Printf("[rules] %q: failed to send delayed frame: %v", c.Name, err)
+				}
+			})
+			return gateway.DROP
+		case ACTION_DUPLICATE:
+			for i := 0; i < c.Count; i++ {
+				emit(f)
+			}
+		}
+		return gateway.PASS
+	})
+}
+
+// scale multiplies a joystick value, keeping it within the valid range
+func scale(v int8, factor float64) int8 {
+	s := math.Round(float64(v) * factor)
+	return int8(math.Max(float64(rnet.MIN_XY_DATA), math.Min(float64(rnet.MAX_XY_DATA), s)))
+}
+
+// Processors returns the pipeline for one direction, in the order the rules appear in the file
+func (s *Set) Processors(dir gateway.Direction, inj Injector) []gateway.Processor {
+	var procs []gateway.Processor
+	for _, c := range s.rules {
+		if c.Action == ACTION_INJECT {
+			continue
+		}
+		for _, d := range c.dirs {
+			if d == dir {
+				procs = append(procs, c.processor(dir, inj))
+			}
+		}
+	}
+	return procs
+}
+
+// Inject sends the periodic frames of the inject rules until ctx is cancelled
+func (s *Set) Inject(ctx context.Context, inj Injector) {
+	for _, c := range s.rules {
+		if c.Action != ACTION_INJECT {
+			continue
+		}
+		go func(c *compiled) {
+			t := time.NewTicker(c.period)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					f := *c.frame
+					if err := inj.Inject(c.dirs[0], &f); err != nil {
+						log.Printf("[rules] %q: failed to inject: %v", c.Name, err)
+					}
+				}
+			}
+		}(c)
+	}
+}
+
+// Apply installs the rules on the gateway, replacing any previous pipeline, and starts the periodic injections.
+// Call the returned function to stop the injections, e.g. before applying a reloaded rules file.
+func (s *Set) Apply(gw *gateway.Gateway) (stop func()) {
+	gw.SetProcessors(gateway.JSM_TO_CHAIR, s.Processors(gateway.JSM_TO_CHAIR, gw))
+	gw.SetProcessors(gateway.CHAIR_TO_JSM, s.Processors(gateway.CHAIR_TO_JSM, gw))
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Inject(ctx, gw)
+	return cancel
+}
+
+// Len returns the number of rules in the set
+func (s *Set) Len() int {
+	return len(s.rules)
+}
@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/gateway"
+)
+
+// recorder collects injected frames in place of the gateway
+type recorder struct {
+	mu     sync.Mutex
+	frames []string
+}
+
+func (r *recorder) Inject(dir gateway.Direction, f *can.Frame) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, dir.String()+" "+f.String())
+	return nil
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.frames...)
+}
+
+// run passes a frame through the pipeline like the gateway would, returning what comes out
+func run(t *testing.T, procs []gateway.Processor, line string) []string {
+	f, err := can.FromLog(line)
+	if err != nil {
+		t.Fatalf("FromLog(%q): %v", line, err)
+	}
+	var out []string
+	emit := func(extra *can.Frame) { out = append(out, extra.String()) }
+	for _, p := range procs {
+		if p.Process(f, emit) == gateway.DROP {
+			return out
+		}
+	}
+	return append(out, f.String())
+}
+
+func TestRules(t *testing.T) {
+	set, err := Parse(strings.NewReader(`{"rules": [
+		{"name": "no horn", "direction": "jsm->chair", "match": {"id": "0C040100", "mask": "FFFFF0FE"}, "action": "drop"},
+		{"name": "half speed", "match": {"id": "02000000", "mask": "FFFFF0FF"}, "action": "scale_axis", "axis": "fwd", "factor": 0.5},
+		{"name": "stop left", "match": {"id": "02000100", "data": "9C??"}, "action": "rewrite", "data": "00"},
+		{"name": "late status", "direction": "chair->jsm", "match": {"id": "00E"}, "action": "delay", "delay": "10ms"},
+		{"name": "echo", "match": {"id": "140C0001"}, "action": "duplicate", "count": 2},
+		{"name": "ping", "direction": "jsm->chair", "action": "inject", "frame": "0C000404#", "period": "5ms"}
+	]}`))
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	if set.Len() != 6 {
+		t.Fatalf("expected 6 rules, got: %d", set.Len())
+	}
+	inj := &recorder{}
+	toChair := set.Processors(gateway.JSM_TO_CHAIR, inj)
+	toJSM := set.Processors(gateway.CHAIR_TO_JSM, inj)
+
+	type test struct {
+		procs []gateway.Processor
+		input string
+		want  []string
+	}
+	tests := []test{
+		{procs: toChair, input: "0C040100#", want: nil},
+		{procs: toChair, input: "0C040201#", want: nil},
+		{procs: toJSM, input: "0C040100#", want: []string{"0C040100#"}},
+		{procs: toChair, input: "02000100#0A64", want: []string{"02000100#0A32"}},
+		{procs: toChair, input: "02000100#9C9C", want: []string{"02000100#00CE"}},
+		{procs: toChair, input: "00E#048C1CBC00000000", want: []string{"00E#048C1CBC00000000"}},
+		{procs: toChair, input: "140C0001#0000", want: []string{"140C0001#0000", "140C0001#0000", "140C0001#0000"}},
+		{procs: toJSM, input: "00E#048C1CBC00000000", want: nil},
+	}
+	for _, test := range tests {
+		got := run(t, test.procs, test.input)
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Fatalf("%s: expected: %v, got: %v", test.input, test.want, got)
+		}
+	}
+
+	// the delayed status frame shows up later
+	time.Sleep(50 * time.Millisecond)
+	if got := inj.get(); len(got) != 1 || got[0] != "chair->jsm 00E#048C1CBC00000000" {
+		t.Fatalf("expected delayed status frame, got: %v", got)
+	}
+
+	stop := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	set.Inject(ctx, inj)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		cancel()
+		close(stop)
+	}()
+	<-stop
+	got := inj.get()
+	if len(got) < 3 || got[1] != "jsm->chair 0C000404#" {
+		t.Fatalf("expected periodic injections, got: %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, bad := range []string{
+		`{"rules": [{"action": "explode"}]}`,
+		`{"rules": [{"direction": "up", "action": "drop"}]}`,
+		`{"rules": [{"match": {"id": "12"}, "action": "drop"}]}`,
+		`{"rules": [{"match": {"mask": "F"}, "action": "drop"}]}`,
+		`{"rules": [{"match": {"id": "123", "data": "0"}, "action": "drop"}]}`,
+		`{"rules": [{"action": "rewrite"}]}`,
+		`{"rules": [{"action": "scale_axis", "axis": "up"}]}`,
+		`{"rules": [{"action": "delay", "delay": "-1s"}]}`,
+		`{"rules": [{"action": "duplicate"}]}`,
+		`{"rules": [{"action": "inject", "frame": "123#00", "period": "1s"}]}`,
+		`{"rules": [{"direction": "jsm->chair", "action": "inject", "frame": "123#0", "period": "1s"}]}`,
+		`{"rules": [{"action": "drop", "typo": true}]}`,
+		`not json`,
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Fatalf("Parse(%s), expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Session is a recorded session, opened for reading
+type Session struct {
+	Dir   string
+	Index Index
+}
+
+// Open reads the index of the session in dir. If the index is missing or out of date,
+// e.g. because the recorder didn't get to close, it is rebuilt from the segment files.
+func Open(dir string) (*Session, error) {
+	s := &Session{Dir: dir}
+	b, err := ioutil.ReadFile(filepath.Join(dir, INDEX_FILE))
+	if err == nil {
+		err = json.Unmarshal(b, &s.Index)
+	}
+	if err != nil || !s.upToDate() {
+		if s.Index, err = Rebuild(dir, DEFAULT_MARK_INTERVAL); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// upToDate checks the index against the segment files on disk
+func (s *Session) upToDate() bool {
+	for _, seg := range s.Index.Segments {
+		fi, err := os.Stat(filepath.Join(s.Dir, seg.File))
+		if err != nil || fi.Size() != seg.Bytes {
+			return false
+		}
+	}
+	_, err := os.Stat(filepath.Join(s.Dir, fmt.Sprintf(SEGMENT_FILE, len(s.Index.Segments))))
+	return os.IsNotExist(err)
+}
+
+// Rebuild indexes the segment files in dir by reading them. A partly written last line is left out.
+func Rebuild(dir string, markInterval time.Duration) (Index, error) {
+	var ix Index
+	for i := 0; ; i++ {
+		name := fmt.Sprintf(SEGMENT_FILE, i)
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return ix, err
+		}
+		ix.Segments = append(ix.Segments, Segment{File: name})
+		r := bufio.NewReader(f)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				break
+			}
+			var rec Record
+			if err := json.Unmarshal(line, &rec); err != nil {
+				f.Close()
+				return ix, fmt.Errorf("%s: record %d: %w", name, ix.Segments[i].Records+1, err)
+			}
+			ix.add(&rec, int64(len(line)), markInterval)
+		}
+		f.Close()
+	}
+	if len(ix.Segments) == 0 {
+		return ix, fmt.Errorf("no session in %s", dir)
+	}
+	return ix, nil
+}
+
+// Duration is the time between the first and the last record
+func (s *Session) Duration() time.Duration {
+	return s.Index.End.Sub(s.Index.Start)
+}
+
+// Reader returns the records of a session in order
+type Reader struct {
+	s    *Session
+	seg  int
+	file *os.File
+	r    *bufio.Reader
+	from time.Time
+}
+
+// Records reads the session from the start
+func (s *Session) Records() (*Reader, error) {
+	return s.Seek(s.Index.Start)
+}
+
+// Seek reads the session from the first record at or after t
+func (s *Session) Seek(t time.Time) (*Reader, error) {
+	rd := &Reader{s: s, from: t}
+	segs := s.Index.Segments
+	rd.seg = sort.Search(len(segs), func(i int) bool { return !segs[i].End.Before(t) })
+	if rd.seg == len(segs) {
+		// past the end, nothing to read
+		return rd, nil
+	}
+	seg := segs[rd.seg]
+	var offset int64
+	// the last mark at or before t
+	if i := sort.Search(len(seg.Marks), func(i int) bool { return seg.Marks[i].Time.After(t) }); i > 0 {
+		offset = seg.Marks[i-1].Offset
+	}
+	if err := rd.open(offset); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+func (rd *Reader) open(offset int64) error {
+	f, err := os.Open(filepath.Join(rd.s.Dir, rd.s.Index.Segments[rd.seg].File))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	rd.file, rd.r = f, bufio.NewReader(f)
+	return nil
+}
+
+// Next returns the next record, or io.EOF at the end of the session
+func (rd *Reader) Next() (Record, error) {
+	for {
+		if rd.file == nil {
+			return Record{}, io.EOF
+		}
+		line, err := rd.r.ReadBytes('\n')
+		if err == io.EOF {
+			// on to the next segment. a partly written last line is dropped, like Rebuild does
+			rd.file.Close()
+			rd.file = nil
+			rd.seg++
+			if rd.seg < len(rd.s.Index.Segments) {
+				if err := rd.open(0); err != nil {
+					return Record{}, err
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return Record{}, err
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return Record{}, fmt.Errorf("%s: %w", rd.s.Index.Segments[rd.seg].File, err)
+		}
+		if rec.Time.Before(rd.from) {
+			// between the mark and the time we seeked to
+			continue
+		}
+		rec.upgrade()
+		return rec, nil
+	}
+}
+
+func (rd *Reader) Close() error {
+	if rd.file == nil {
+		return nil
+	}
+	err := rd.file.Close()
+	rd.file = nil
+	return err
+}
+
+// ErrStop can be returned by a Replay callback to end the replay early, Replay then returns nil
+var ErrStop = errors.New("stop replay")
+
+// Replay calls fn with every record from time from, spaced out like they were recorded.
+// speed scales time, 2 plays twice as fast and 0 doesn't wait at all.
+func (s *Session) Replay(ctx context.Context, from time.Time, speed float64, fn func(Record) error) error {
+	rd, err := s.Seek(from)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	start := time.Now()
+	var first time.Time
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first.IsZero() {
+			first = rec.Time
+		}
+		if speed > 0 {
+			due := start.Add(time.Duration(float64(rec.Time.Sub(first)) / speed))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fn(rec); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+const (
+	INDEX_FILE            = "index.json"
+	SEGMENT_FILE          = "session-%04d.jsonl"
+	DEFAULT_SEGMENT_SIZE  = 16 << 20 // bytes, about a minute of both buses plus sensors
+	DEFAULT_MARK_INTERVAL = time.Second
+	DEFAULT_QUEUE_SIZE    = 1 << 14 // records, a few seconds of both buses
+)
+
+// ErrDropped is returned by Record when the recorder is too far behind to take another record
+var ErrDropped = errors.New("session: recorder queue full, record dropped")
+
+// Mark is a point in a segment a reader can start from
+type Mark struct {
+	Time   time.Time `json:"t"`
+	Offset int64     `json:"offset"` // of the first record at or after Time
+}
+
+type Segment struct {
+	File    string    `json:"file"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Records int       `json:"records"`
+	Bytes   int64     `json:"bytes"`
+	Marks   []Mark    `json:"marks"`
+}
+
+// Index is the table of contents of a session, kept in index.json
+type Index struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Segments []Segment `json:"segments"`
+}
+
+// add updates the index for a record of n bytes written to the last segment
+func (ix *Index) add(rec *Record, n int64, markInterval time.Duration) (marked bool) {
+	seg := &ix.Segments[len(ix.Segments)-1]
+	if seg.Records == 0 {
+		seg.Start = rec.Time
+	}
+	if len(seg.Marks) == 0 || rec.Time.Sub(seg.Marks[len(seg.Marks)-1].Time) >= markInterval {
+		seg.Marks = append(seg.Marks, Mark{Time: rec.Time, Offset: seg.Bytes})
+		marked = true
+	}
+	if rec.Time.After(seg.End) {
+		seg.End = rec.Time
+	}
+	seg.Records++
+	seg.Bytes += n
+	if ix.Start.IsZero() {
+		ix.Start = rec.Time
+	}
+	if rec.Time.After(ix.End) {
+		ix.End = rec.Time
+	}
+	return marked
+}
+
+type Config struct {
+	Dir          string        // created if it doesn't exist, must not hold a session already
+	SegmentSize  int64         // bytes per segment file, DEFAULT_SEGMENT_SIZE if 0
+	MarkInterval time.Duration // time between index marks, DEFAULT_MARK_INTERVAL if 0
+	QueueSize    int           // records waiting to be written before more are dropped, DEFAULT_QUEUE_SIZE if 0
+}
+
+// Recorder writes a session. It is safe for concurrent use, records are kept in the order they are recorded.
+// Recording never waits for the disk, records are queued and written by a goroutine of the recorder's own,
+// so a slow disk can't hold up the gateway. If it falls too far behind, records are dropped and counted.
+type Recorder struct {
+	cfg Config
+
+	queue    chan Record
+	stop     chan struct{} // closed by Close, the writer then drains the queue
+	stopOnce sync.Once
+	finished chan struct{} // closed when the writer is done
+	dropped  uint64        // atomic
+
+	mu    sync.Mutex // held by the writer while it writes
+	file  *os.File
+	w     *bufio.Writer
+	index Index
+	err   error // first error, nothing is written after it
+}
+
+func NewRecorder(cfg Config) (*Recorder, error) {
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = DEFAULT_SEGMENT_SIZE
+	}
+	if cfg.MarkInterval <= 0 {
+		cfg.MarkInterval = DEFAULT_MARK_INTERVAL
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DEFAULT_QUEUE_SIZE
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Dir, fmt.Sprintf(SEGMENT_FILE, 0))); err == nil {
+		return nil, fmt.Errorf("%s already holds a session", cfg.Dir)
+	}
+	r := &Recorder{
+		cfg:      cfg,
+		queue:    make(chan Record, cfg.QueueSize),
+		stop:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	go r.run()
+	return r, nil
+}
+
+// run writes queued records until Close, then whatever is left in the queue
+func (r *Recorder) run() {
+	defer close(r.finished)
+	for {
+		select {
+		case rec := <-r.queue:
+			r.write(rec)
+		case <-r.stop:
+			for {
+				select {
+				case rec := <-r.queue:
+					r.write(rec)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// rotate finishes the current segment, if any, and starts the next one
+func (r *Recorder) rotate() error {
+	if r.file != nil {
+		if err := r.closeSegment(); err != nil {
+			return err
+		}
+		if err := r.writeIndex(); err != nil {
+			return err
+		}
+	}
+	name := fmt.Sprintf(SEGMENT_FILE, len(r.index.Segments))
+	f, err := os.OpenFile(filepath.Join(r.cfg.Dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	r.file, r.w = f, bufio.NewWriter(f)
+	r.index.Segments = append(r.index.Segments, Segment{File: name})
+	return nil
+}
+
+func (r *Recorder) closeSegment() error {
+	err := r.w.Flush()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file, r.w = nil, nil
+	return err
+}
+
+// writeIndex replaces index.json, via a temporary file so a reader never sees half of it
+func (r *Recorder) writeIndex() error {
+	b, err := json.MarshalIndent(r.index, "", " ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(r.cfg.Dir, INDEX_FILE+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(r.cfg.Dir, INDEX_FILE))
+}
+
+// Record queues rec to be written to the session, with the current time if rec.Time is zero. It doesn't wait for
+// the write, errors writing are returned by Close. If the queue is full the record is dropped and ErrDropped returned.
+// A sensor reading is always stamped with FORMAT_VERSION, whatever Version it had, the recorder only writes the
+// current format, a reading from an older session is upgraded when it's read.
+func (r *Recorder) Record(rec Record) error {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if rec.Sensor != nil {
+		s := *rec.Sensor
+		s.Version = FORMAT_VERSION
+		rec.Sensor = &s
+	}
+	select {
+	case <-r.stop:
+		return fmt.Errorf("recorder closed")
+	default:
+	}
+	select {
+	case r.queue <- rec:
+		return nil
+	default:
+		atomic.AddUint64(&r.dropped, 1)
+		return ErrDropped
+	}
+}
+
+// Dropped returns how many records didn't make it into the session because the queue was full
+func (r *Recorder) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// write writes a record to the current segment, starting a new one if it's full
+func (r *Recorder) write(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil || r.file == nil {
+		return
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		r.err = err
+		return
+	}
+	b = append(b, '\n')
+	if seg := r.index.Segments[len(r.index.Segments)-1]; seg.Records > 0 && seg.Bytes+int64(len(b)) > r.cfg.SegmentSize {
+		if r.err = r.rotate(); r.err != nil {
+			return
+		}
+	}
+	if r.index.add(&rec, int64(len(b)), r.cfg.MarkInterval) {
+		// a crash loses at most a mark's worth of records
+		if r.err = r.w.Flush(); r.err != nil {
+			return
+		}
+	}
+	_, r.err = r.w.Write(b)
+}
+
+// the helpers below stamp records with the current time. they ignore errors, a write error is returned by Close
+// and dropped records are counted, so that callers on a hot path like the gateway don't have to deal with them
+// for every frame.
+
+func (r *Recorder) Frame(side rnet.Side, iface string, f *can.Frame) {
+	r.Record(FrameRecord(time.Now(), side, iface, f))
+}
+
+func (r *Recorder) Sensor(s Sensor) {
+	r.Record(Record{Kind: KIND_SENSOR, Sensor: &s})
+}
+
+func (r *Recorder) Decision(d Decision) {
+	r.Record(Record{Kind: KIND_DECISION, Decision: &d})
+}
+
+func (r *Recorder) Chair(c Chair) {
+	r.Record(Record{Kind: KIND_CHAIR, Chair: &c})
+}
+
+func (r *Recorder) Fault(reason string) {
+	r.Record(Record{Kind: KIND_FAULT, Fault: reason})
+}
+
+// Index returns a copy of the index of what has been written so far
+func (r *Recorder) Index() Index {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ix := r.index
+	ix.Segments = append([]Segment(nil), ix.Segments...)
+	return ix
+}
+
+// Close writes the records still queued, notes how many were dropped, if any, flushes the last segment and
+// writes the index. It returns the first error the recorder ran into.
+func (r *Recorder) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.finished
+	if n := r.Dropped(); n > 0 {
+		r.write(Record{Time: time.Now(), Kind: KIND_FAULT, Fault: fmt.Sprintf("recorder fell behind, %d records dropped", n)})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return r.err
+	}
+	err := r.closeSegment()
+	if err == nil {
+		err = r.writeIndex()
+	}
+	if r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// tap records every frame read from an interface
+type tap struct {
+	can.Interface
+	r     *Recorder
+	side  rnet.Side
+	iface string
+}
+
+func (t *tap) Read() (*can.Frame, error) {
+	f, err := t.Interface.Read()
+	if err == nil && f != nil {
+		t.r.Frame(t.side, t.iface, f)
+	}
+	return f, err
+}
+
+// Close closes the wrapped interface, if it can be closed
+func (t *tap) Close() error {
+	if c, ok := t.Interface.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Tap wraps an interface on one side of the gateway so that every frame read from it is recorded
+func (r *Recorder) Tap(side rnet.Side, iface string, i can.Interface) can.Interface {
+	return &tap{Interface: i, r: r, side: side, iface: iface}
+}
@@ -0,0 +1,156 @@
+package session
+
+// a session is everything that happened during a chair test, in one place and in time order:
+// raw frames from both sides of the gateway (with movement frames decoded), sensor readings,
+// avoidance decisions, chair state and faults.
+//
+// a session is a directory of segment files, session-0000.jsonl, session-0001.jsonl, ..., with one
+// JSON record per line. a new segment is started once the current one reaches the size limit.
+// index.json lists the segments with their time range and a mark every second or so, giving the
+// byte offset of the first record at that time, so a reader can jump to any point without scanning.
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+type Kind string
+
+const (
+	KIND_FRAME    Kind = "frame"    // a frame seen on one side of the gateway
+	KIND_SENSOR   Kind = "sensor"   // a range reading
+	KIND_DECISION Kind = "decision" // what the avoidance did with a joystick input
+	KIND_CHAIR    Kind = "chair"    // where the chair is and where it is heading
+	KIND_FAULT    Kind = "fault"    // something went wrong, e.g. the gateway went into fail-safe mode
+)
+
+// Vector is a joystick position or a pushback, X is side and Y is forward, like demo.Vector2D
+type Vector struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Joystick is a movement frame, decoded by rnet.ConvertDataToJoy
+type Joystick struct {
+	Side float64 `json:"side"`
+	Fwd  float64 `json:"fwd"`
+}
+
+const (
+	// FORMAT_VERSION is stamped on every sensor record by the recorder, so readers know what the numbers mean.
+	// records without one are from before the demo measured in meters, their ranges are in demo pixels.
+	FORMAT_VERSION = 1
+	// LEGACY_PIXELS_PER_METER is the scale of the demo at the time, for reading those records
+	LEGACY_PIXELS_PER_METER = 25
+)
+
+type Sensor struct {
+	Version   int     `json:"v,omitempty"` // FORMAT_VERSION of the record, 0 for pixels
+	Name      string  `json:"name"`
+	AimDeg    float64 `json:"aim_deg"`
+	MountX    float64 `json:"mount_x,omitempty"` // where the sensor sits, meters right of the chair's center
+	MountY    float64 `json:"mount_y,omitempty"` // meters ahead of it
+	Meters    float64 `json:"meters"`            // from the sensor
+	Threshold float64 `json:"threshold"`
+}
+
+type Decision struct {
+	Input    Vector `json:"input"`
+	Pushback Vector `json:"pushback"`
+	Output   Vector `json:"output"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type Chair struct {
+	X          float64 `json:"x"`                   // position in meters, east
+	Y          float64 `json:"y"`                   // north
+	BearingDeg float64 `json:"bearing_deg"`         // clockwise from north
+	Speed      int     `json:"speed"`               // speed level, 0 through 4
+	Velocity   float64 `json:"velocity,omitempty"`  // m/s forward
+	TurnRate   float64 `json:"turn_rate,omitempty"` // rad/s, positive turns left
+}
+
+// Record is one line of a segment file. Which of the optional fields is set depends on the Kind.
+type Record struct {
+	Time time.Time `json:"t"`
+	Kind Kind      `json:"kind"`
+
+	// KIND_FRAME
+	Side     string    `json:"side,omitempty"`  // rnet.Side the frame was seen on, "jsm" or "chair"
+	Iface    string    `json:"iface,omitempty"` // interface name, if there is one
+	Frame    string    `json:"frame,omitempty"` // in candump format
+	Joystick *Joystick `json:"joy,omitempty"`   // set for movement frames
+
+	Sensor   *Sensor   `json:"sensor,omitempty"`
+	Decision *Decision `json:"decision,omitempty"`
+	Chair    *Chair    `json:"chair,omitempty"`
+	Fault    string    `json:"fault,omitempty"`
+}
+
+// upgrade turns a record read from an older session into one of the current FORMAT_VERSION
+func (r *Record) upgrade() {
+	if r.Sensor != nil && r.Sensor.Version == 0 {
+		r.Sensor.Meters /= LEGACY_PIXELS_PER_METER
+		r.Sensor.Threshold /= LEGACY_PIXELS_PER_METER
+		r.Sensor.Version = FORMAT_VERSION
+	}
+}
+
+// FrameRecord builds the record of a frame seen on one side of the gateway, decoding movement frames
+func FrameRecord(t time.Time, side rnet.Side, iface string, f *can.Frame) Record {
+	r := Record{Time: t, Kind: KIND_FRAME, Side: side.String(), Iface: iface, Frame: f.String()}
+	if rnet.IsMovementFrame(f.ID) && f.DLC >= 2 {
+		fwd, s := rnet.ConvertDataToJoy(f.Data[0], f.Data[1])
+		r.Joystick = &Joystick{Side: s, Fwd: fwd}
+	}
+	return r
+}
+
+// CANFrame parses the frame of a KIND_FRAME record
+func (r Record) CANFrame() (*can.Frame, error) {
+	if r.Kind != KIND_FRAME {
+		return nil, fmt.Errorf("%s record has no frame", r.Kind)
+	}
+	return can.FromLog(r.Frame)
+}
+
+// FromSide returns the side of the gateway a KIND_FRAME record was seen on
+func (r Record) FromSide() rnet.Side {
+	switch r.Side {
+	case rnet.SIDE_JSM.String():
+		return rnet.SIDE_JSM
+	case rnet.SIDE_CHAIR.String():
+		return rnet.SIDE_CHAIR
+	}
+	return rnet.SIDE_ANY
+}
+
+func (r Record) String() string {
+	ts := r.Time.Format("15:04:05.000000")
+	switch r.Kind {
+	case KIND_FRAME:
+		if r.Joystick != nil {
+			return fmt.Sprintf("%s %s [%s] %s side=%+.2f fwd=%+.2f", ts, r.Kind, r.Side, r.Frame, r.Joystick.Side, r.Joystick.Fwd)
+		}
+		return fmt.Sprintf("%s %s [%s] %s", ts, r.Kind, r.Side, r.Frame)
+	case KIND_SENSOR:
+		if r.Sensor != nil {
+			return fmt.Sprintf("%s %s %s %.2fm/%.2fm", ts, r.Kind, r.Sensor.Name, r.Sensor.Meters, r.Sensor.Threshold)
+		}
+	case KIND_DECISION:
+		if d := r.Decision; d != nil {
+			return fmt.Sprintf("%s %s in=(%.3f,%.3f) push=(%.3f,%.3f) out=(%.3f,%.3f) %s",
+				ts, r.Kind, d.Input.X, d.Input.Y, d.Pushback.X, d.Pushback.Y, d.Output.X, d.Output.Y, d.Reason)
+		}
+	case KIND_CHAIR:
+		if c := r.Chair; c != nil {
+			return fmt.Sprintf("%s %s (%.2f,%.2f) bearing=%.1f speed=%d", ts, r.Kind, c.X, c.Y, c.BearingDeg, c.Speed)
+		}
+	case KIND_FAULT:
+		return fmt.Sprintf("%s %s %s", ts, r.Kind, r.Fault)
+	}
+	return fmt.Sprintf("%s %s", ts, r.Kind)
+}
@@ -0,0 +1,304 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/team23asu/pican/pkg/can"
+	"github.com/team23asu/pican/pkg/rnet"
+)
+
+// record writes n seconds of a fake session, a movement frame on each side, a sensor reading
+// and a decision every 100ms, returning the start time
+func record(t *testing.T, dir string, seconds int) time.Time {
+	r, err := NewRecorder(Config{Dir: dir, SegmentSize: 4096})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	start := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	f, _ := can.FromLog("02000100#0A64")
+	for i := 0; i < seconds*10; i++ {
+		ts := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		for _, rec := range []Record{
+			FrameRecord(ts, rnet.SIDE_JSM, "can0", f),
+			{Time: ts, Kind: KIND_SENSOR, Sensor: &Sensor{Name: "front", AimDeg: 90, Meters: float64(i), Threshold: 25}},
+			{Time: ts, Kind: KIND_DECISION, Decision: &Decision{Input: Vector{0.1, 1}, Output: Vector{0.1, 0.5}}},
+			FrameRecord(ts, rnet.SIDE_CHAIR, "can1", f),
+		} {
+			if err := r.Record(rec); err != nil {
+				t.Fatalf("Record: %v", err)
+			}
+		}
+	}
+	if err := r.Record(Record{Time: start.Add(time.Duration(seconds) * time.Second), Kind: KIND_FAULT, Fault: "boom"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return start
+}
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+	start := record(t, dir, 5)
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(s.Index.Segments) < 2 {
+		t.Fatalf("expected the session to rotate into several segments, got: %d", len(s.Index.Segments))
+	}
+	if s.Duration() != 5*time.Second {
+		t.Fatalf("expected a 5s session, got: %s", s.Duration())
+	}
+
+	rd, err := s.Records()
+	if err != nil {
+		t.Fatalf("Records: %v", err)
+	}
+	n := 0
+	var last Record
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if rec.Kind == KIND_FRAME && (rec.Joystick == nil || rec.Joystick.Fwd != 1) {
+			t.Fatalf("expected movement frames to be decoded, got: %s", rec)
+		}
+		n, last = n+1, rec
+	}
+	if n != 5*10*4+1 || last.Kind != KIND_FAULT || last.Fault != "boom" {
+		t.Fatalf("expected %d records ending with the fault, got: %d, %s", 5*10*4+1, n, last)
+	}
+
+	type test struct {
+		at     time.Duration
+		sensor float64 // expected reading of the first sensor record
+	}
+	for _, test := range []test{{0, 0}, {1234 * time.Millisecond, 13}, {3 * time.Second, 30}, {4900 * time.Millisecond, 49}} {
+		rd, err := s.Seek(start.Add(test.at))
+		if err != nil {
+			t.Fatalf("Seek(%s): %v", test.at, err)
+		}
+		rec, err := rd.Next()
+		for err == nil && rec.Kind != KIND_SENSOR {
+			rec, err = rd.Next()
+		}
+		rd.Close()
+		if err != nil || rec.Sensor.Meters != test.sensor {
+			t.Fatalf("Seek(%s), expected sensor reading %.0f, got: %s (%v)", test.at, test.sensor, rec, err)
+		}
+	}
+
+	// replaying keeps the recorded timing, scaled by speed
+	var kinds []Kind
+	began := time.Now()
+	err = s.Replay(context.Background(), start.Add(4*time.Second), 10, func(r Record) error {
+		kinds = append(kinds, r.Kind)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if took := time.Since(began); took < 90*time.Millisecond || len(kinds) != 41 {
+		t.Fatalf("expected 41 records over 100ms, got: %d over %s", len(kinds), took)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	dir := t.TempDir()
+	record(t, dir, 3)
+	want, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	// as if the recorder died without writing the index, halfway through a line
+	os.Remove(filepath.Join(dir, INDEX_FILE))
+	last := filepath.Join(dir, want.Index.Segments[len(want.Index.Segments)-1].File)
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"t":"2021-10-01T12:00:09Z","kind":"fra`)
+	f.Close()
+
+	got, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(got.Index.Segments) != len(want.Index.Segments) || got.Index.End != want.Index.End {
+		t.Fatalf("expected rebuilt index to match, got: %+v, expected: %+v", got.Index, want.Index)
+	}
+	for i, seg := range got.Index.Segments {
+		if seg.Records != want.Index.Segments[i].Records || len(seg.Marks) != len(want.Index.Segments[i].Marks) {
+			t.Fatalf("segment %d: expected %+v, got: %+v", i, want.Index.Segments[i], seg)
+		}
+	}
+
+	if _, err := NewRecorder(Config{Dir: dir}); err == nil {
+		t.Fatalf("expected NewRecorder to refuse overwriting a session")
+	}
+}
+
+// sensor records from before FORMAT_VERSION have the demo's pixels, they're read as meters
+func TestSensorVersion(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"t":"2021-10-01T12:00:00Z","kind":"sensor","sensor":{"name":"front-center","aim_deg":90,"meters":50,"threshold":25}}` + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf(SEGMENT_FILE, 0)), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	rd, err := s.Records()
+	if err != nil {
+		t.Fatalf("Records: %v", err)
+	}
+	defer rd.Close()
+	rec, err := rd.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	want := Sensor{Version: FORMAT_VERSION, Name: "front-center", AimDeg: 90, Meters: 2, Threshold: 1}
+	if *rec.Sensor != want {
+		t.Fatalf("Next(), expected: %+v, got: %+v", want, *rec.Sensor)
+	}
+
+	// and new ones are stamped, so they aren't converted again
+	dir = t.TempDir()
+	r, err := NewRecorder(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	r.Sensor(Sensor{Name: "front-center", AimDeg: 90, Meters: 2, Threshold: 1})
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if s, err = Open(dir); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if rd, err = s.Records(); err != nil {
+		t.Fatalf("Records: %v", err)
+	}
+	defer rd.Close()
+	if rec, err = rd.Next(); err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if *rec.Sensor != want {
+		t.Fatalf("Next(), expected: %+v, got: %+v", want, *rec.Sensor)
+	}
+}
+
+func TestTap(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	bus := can.NewBus()
+	jsm := bus.Connect()
+	tapped := r.Tap(rnet.SIDE_JSM, "vcan0", bus.Connect())
+	f, _ := can.FromLog("02000100#0A64")
+	jsm.Send(f)
+	if _, err := tapped.Read(); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	r.Fault("boom")
+	if err := tapped.(io.Closer).Close(); err != nil {
+		t.Fatalf("expected Close to reach the node, got: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	rd, _ := s.Records()
+	defer rd.Close()
+	for _, want := range []string{"frame [jsm] 02000100#0A64 side=-0.10 fwd=+1.00", "fault boom"} {
+		rec, err := rd.Next()
+		if err != nil || rec.String()[16:] != want {
+			t.Fatalf("expected %q, got: %q (%v)", want, rec, err)
+		}
+	}
+}
+
+// a stalled disk mustn't hold up Record, the gateway calls it for every frame
+func TestBlockedWriter(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder(Config{Dir: dir, QueueSize: 4})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	r.mu.Lock() // the writer holds it while it writes, so it's stuck as if the disk was
+	f, _ := can.FromLog("02000100#0A64")
+	done := make(chan int)
+	go func() {
+		dropped := 0
+		for i := 0; i < 20; i++ {
+			if err := r.Record(FrameRecord(time.Time{}, rnet.SIDE_JSM, "can0", f)); err == ErrDropped {
+				dropped++
+			} else if err != nil {
+				t.Errorf("Record: %v", err)
+			}
+		}
+		done <- dropped
+	}()
+	var dropped int
+	select {
+	case dropped = <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected Record not to wait for a blocked writer")
+	}
+	if dropped == 0 || uint64(dropped) != r.Dropped() {
+		t.Fatalf("expected dropped records to be counted, returned: %d, Dropped(): %d", dropped, r.Dropped())
+	}
+	r.mu.Unlock()
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	rd, _ := s.Records()
+	defer rd.Close()
+	frames := 0
+	var last Record
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if rec.Kind == KIND_FRAME {
+			frames++
+		}
+		last = rec
+	}
+	if frames != 20-dropped {
+		t.Fatalf("expected the %d queued frames to be written, got: %d", 20-dropped, frames)
+	}
+	want := fmt.Sprintf("recorder fell behind, %d records dropped", dropped)
+	if last.Kind != KIND_FAULT || last.Fault != want {
+		t.Fatalf("expected the drops to be noted, got: %q", last)
+	}
+}